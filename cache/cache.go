@@ -68,3 +68,30 @@ func (r *Cache) Set(ctx context.Context, key string, value interface{}) error {
 	}
 	return nil
 }
+
+func (r *Cache) RPush(ctx context.Context, key string, values ...interface{}) error {
+	return r.redisDB.RPush(ctx, key, values...).Err()
+}
+
+func (r *Cache) LPop(ctx context.Context, key string) (string, error) {
+	strCmd := r.redisDB.LPop(ctx, key)
+	if strCmd.Err() != nil {
+		if strCmd.Err() == redis.Nil {
+			return "", &NotInRedisCacheError{message: "list is empty or does not exist in redis"}
+		}
+		return "", strCmd.Err()
+	}
+	return strCmd.Val(), nil
+}
+
+func (r *Cache) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return r.redisDB.Eval(ctx, script, keys, args...).Result()
+}
+
+func (r *Cache) SetBit(ctx context.Context, key string, offset int64, value int) error {
+	return r.redisDB.SetBit(ctx, key, offset, value).Err()
+}
+
+func (r *Cache) GetRange(ctx context.Context, key string, start, end int64) (string, error) {
+	return r.redisDB.GetRange(ctx, key, start, end).Result()
+}
@@ -0,0 +1,223 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/retry"
+)
+
+// State is the state of a circuit breaker
+type State int
+
+const (
+	//	Closed lets every call through, counting failures
+	Closed State = iota
+	//	Open refuses every call until the open timeout elapses
+	Open
+	//	HalfOpen lets a limited number of trial calls through; they close the breaker if they all succeed, and open it
+	//	again as soon as one fails
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+var (
+	// ErrOpen is returned for calls refused by an open breaker; it is wrapped with retry.Permanent(), so that retry.Do()
+	// stops retrying at once, and should be checked with errors.Is()
+	ErrOpen = errors.New("circuit breaker is open")
+)
+
+// Config configures a Breaker; a breaker trips on whichever of its trip conditions is met first
+type Config struct {
+	Name string
+	//	ConsecutiveFailures trips the breaker after this many failures in a row; disabled if zero
+	ConsecutiveFailures int
+	//	FailureRatio trips the breaker once this fraction (0-1) of calls have failed in the current window, provided at
+	//	least MinRequests calls were made; disabled if zero
+	FailureRatio float64
+	MinRequests  int
+	//	Window is the period after which the counts of a closed breaker are reset; counts are never reset if zero
+	Window time.Duration
+	//	OpenTimeout is how long the breaker stays open before letting trial calls through
+	OpenTimeout time.Duration
+	//	HalfOpenRequests is the number of trial calls let through, which must all succeed to close the breaker;
+	//	defaults to 1
+	HalfOpenRequests int
+	//	IsFailure decides whether an error counts as a failure; by default, every error but context cancellation does
+	IsFailure func(err error) bool
+	//	OnStateChange is called on every change of state, e.g. for logging and metrics
+	OnStateChange func(name string, from, to State)
+}
+
+// Breaker stops calls to a failing dependency, such as an RPC node, for a while, rather than letting every caller
+// fail on its own
+type Breaker struct {
+	mu          *sync.Mutex
+	cfg         Config
+	state       State
+	openedAt    time.Time
+	windowStart time.Time
+	calls       int
+	failures    int
+	consecutive int
+	trials      int
+	successes   int
+}
+
+// New instantiates a closed breaker
+func New(cfg Config) *Breaker {
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(err error) bool {
+			return err != nil && !errors.Is(err, context.Canceled)
+		}
+	}
+	return &Breaker{mu: &sync.Mutex{}, cfg: cfg, windowStart: time.Now()}
+}
+
+// Execute runs fn if the breaker allows it, recording its outcome
+func (b *Breaker) Execute(fn retry.RunnerFunc) error {
+	if err := b.Allow(); err != nil {
+		return err
+	}
+	err := fn()
+	b.Record(err)
+	return err
+}
+
+// Allow reports whether a call may be made, returning ErrOpen otherwise; every allowed call must have its outcome
+// reported with Record()
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	from := b.state
+	if b.state == Open && time.Since(b.openedAt) >= b.cfg.OpenTimeout {
+		b.setState(HalfOpen)
+	}
+	allowed := true
+	switch b.state {
+	case Open:
+		allowed = false
+	case HalfOpen:
+		if b.trials >= b.cfg.HalfOpenRequests {
+			allowed = false
+		} else {
+			b.trials++
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	if !allowed {
+		return retry.Permanent(ErrOpen)
+	}
+	return nil
+}
+
+// Record reports the outcome of an allowed call, tripping or closing the breaker as needed
+func (b *Breaker) Record(err error) {
+	failed := b.cfg.IsFailure(err)
+
+	b.mu.Lock()
+	from := b.state
+	switch b.state {
+	case Closed:
+		if b.cfg.Window > 0 && time.Since(b.windowStart) >= b.cfg.Window {
+			b.resetCounts()
+		}
+		b.calls++
+		if failed {
+			b.failures++
+			b.consecutive++
+		} else {
+			b.consecutive = 0
+		}
+		if b.shouldTrip() {
+			b.setState(Open)
+		}
+	case HalfOpen:
+		if b.trials == 0 {
+			//	the outcome of a call allowed before the breaker opened
+			break
+		}
+		if failed {
+			b.setState(Open)
+			break
+		}
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenRequests {
+			b.setState(Closed)
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// release gives back the trial slot of an allowed call that was never made
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == HalfOpen && b.trials > b.successes {
+		b.trials--
+	}
+}
+
+// State reports the current state of the breaker; an open breaker whose timeout has elapsed is reported as
+// half-open, although it only changes state on the next call
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == Open && time.Since(b.openedAt) >= b.cfg.OpenTimeout {
+		return HalfOpen
+	}
+	return b.state
+}
+
+func (b *Breaker) shouldTrip() bool {
+	if b.cfg.ConsecutiveFailures > 0 && b.consecutive >= b.cfg.ConsecutiveFailures {
+		return true
+	}
+	return b.cfg.FailureRatio > 0 && b.calls >= b.cfg.MinRequests &&
+		float64(b.failures)/float64(b.calls) >= b.cfg.FailureRatio
+}
+
+// setState moves the breaker to a new state, resetting the counts of the old one; must be called with the lock held
+func (b *Breaker) setState(state State) {
+	b.state = state
+	b.resetCounts()
+	b.trials = 0
+	b.successes = 0
+	if state == Open {
+		b.openedAt = time.Now()
+	}
+}
+
+func (b *Breaker) resetCounts() {
+	b.windowStart = time.Now()
+	b.calls = 0
+	b.failures = 0
+	b.consecutive = 0
+}
+
+func (b *Breaker) notify(from, to State) {
+	if from != to && b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(b.cfg.Name, from, to)
+	}
+}
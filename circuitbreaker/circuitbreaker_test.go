@@ -0,0 +1,91 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/circuitbreaker"
+	"github.com/coherentopensource/go-service-framework/retry"
+)
+
+func TestBreaker(t *testing.T) {
+	failure := errors.New("node unavailable")
+	fail := func() error { return failure }
+	succeed := func() error { return nil }
+
+	var transitions []string
+	breaker := circuitbreaker.New(circuitbreaker.Config{
+		Name:                "node",
+		ConsecutiveFailures: 3,
+		OpenTimeout:         50 * time.Millisecond,
+		HalfOpenRequests:    2,
+		OnStateChange: func(name string, from, to circuitbreaker.State) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+
+	//	Three failures in a row trip the breaker, which then refuses calls
+	breaker.Execute(fail)
+	breaker.Execute(fail)
+	breaker.Execute(succeed)
+	breaker.Execute(fail)
+	breaker.Execute(fail)
+	if breaker.State() != circuitbreaker.Closed {
+		t.Fatalf("Expected a success to reset the consecutive failures, got %s", breaker.State())
+	}
+	breaker.Execute(fail)
+	if err := breaker.Execute(succeed); !errors.Is(err, circuitbreaker.ErrOpen) {
+		t.Fatalf("Expected the breaker to be open, got: %v", err)
+	}
+
+	//	An open breaker stops retries at once
+	attempts := 0
+	policy := retry.Constant(time.Millisecond)
+	policy.MaxAttempts = 5
+	retry.Do(context.Background(), policy, func() error {
+		attempts++
+		return breaker.Execute(succeed)
+	})
+	if attempts != 1 {
+		t.Errorf("Expected an open breaker to stop retries, got %d attempts", attempts)
+	}
+
+	//	After the timeout, trial calls are let through; a failure reopens the breaker, while successes close it
+	time.Sleep(60 * time.Millisecond)
+	breaker.Execute(fail)
+	if breaker.State() != circuitbreaker.Open {
+		t.Fatalf("Expected a failed trial to reopen the breaker, got %s", breaker.State())
+	}
+	time.Sleep(60 * time.Millisecond)
+	breaker.Execute(succeed)
+	breaker.Execute(succeed)
+	if breaker.State() != circuitbreaker.Closed {
+		t.Fatalf("Expected successful trials to close the breaker, got %s", breaker.State())
+	}
+
+	expected := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(expected) {
+		t.Fatalf("Expected transitions %v, got %v", expected, transitions)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Errorf("Expected transitions %v, got %v", expected, transitions)
+			break
+		}
+	}
+
+	//	A failure ratio trips the breaker once enough calls were made
+	ratio := circuitbreaker.New(circuitbreaker.Config{FailureRatio: 0.5, MinRequests: 4, OpenTimeout: time.Minute})
+	ratio.Execute(fail)
+	ratio.Execute(fail)
+	ratio.Execute(succeed)
+	if ratio.State() != circuitbreaker.Closed {
+		t.Errorf("Expected the breaker to wait for the minimum number of calls, got %s", ratio.State())
+	}
+	ratio.Execute(succeed)
+	if ratio.State() != circuitbreaker.Open {
+		t.Errorf("Expected a 50%% failure ratio to trip the breaker, got %s", ratio.State())
+	}
+}
@@ -0,0 +1,40 @@
+package circuitbreaker
+
+import (
+	"context"
+
+	"github.com/coherentopensource/go-service-framework/rate_limiter"
+)
+
+type limiter struct {
+	breaker *Breaker
+	next    rate_limiter.Limiter
+}
+
+// Limiter guards a rate limiter (optional) with a breaker: Wait fails fast with ErrOpen while the breaker is open,
+// and the outcome of every call, as reported by rate_limiter.RateLimitedClient and the middleware package, feeds the
+// breaker. Outcomes are passed on to the rate limiter if it adapts to them
+func Limiter(breaker *Breaker, next rate_limiter.Limiter) rate_limiter.Limiter {
+	return &limiter{breaker: breaker, next: next}
+}
+
+func (l *limiter) Wait(ctx context.Context) error {
+	if err := l.breaker.Allow(); err != nil {
+		return err
+	}
+	if l.next == nil {
+		return nil
+	}
+	if err := l.next.Wait(ctx); err != nil {
+		l.breaker.release()
+		return err
+	}
+	return nil
+}
+
+func (l *limiter) Observe(err error) {
+	l.breaker.Record(err)
+	if observer, ok := l.next.(rate_limiter.Observer); ok {
+		observer.Observe(err)
+	}
+}
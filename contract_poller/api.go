@@ -8,17 +8,23 @@ func (p *Poller) Insights() map[string]map[string]int {
 		"fetch-pool":         p.fetchPool.Insights(),
 		"accumulate-pool":    p.accumulatePool.Insights(),
 		"write-pool":         p.writePool.Insights(),
+		"pipeline":           p.pipeline.Insights(),
 	}
 }
 
+// Pause drains the pipeline, then holds the poller until it is resumed; if the drain times out, the queued jobs are
+// abandoned and the batch in progress is left for re-polling on resume, so no partially written block is skipped
 func (p *Poller) Pause() {
 	p.modeMu.Lock()
 	defer p.modeMu.Unlock()
 
-	p.writePool.FlushAndRestart()
-	p.accumulatePool.FlushAndRestart()
-	p.fetchPool.FlushAndRestart()
-	p.getAddressPool.FlushAndRestart()
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.DrainTimeout)
+	defer cancel()
+
+	if report := p.pipeline.Drain(ctx); !report.Complete() {
+		p.logger.Warnf("Pause timed out; %d queued jobs abandoned, the current batch will be re-polled on resume", report.Abandoned())
+	}
+
 	p.mode = ModePaused
 }
 
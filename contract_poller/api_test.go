@@ -0,0 +1,36 @@
+package contract_poller
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPause(t *testing.T) {
+	//	A config built by hand, without a drain timeout, still lets Pause() finish the blocks in flight
+	cfg := testConfig()
+	cfg.DrainTimeout = 0
+	driver := newFakeDriver(100)
+	driver.delay = 50 * time.Millisecond
+	p := newTestPoller(t, cfg, driver, newFakeCache())
+	if p.cfg.DrainTimeout <= 0 {
+		t.Errorf("Expected a default drain timeout, got %v", p.cfg.DrainTimeout)
+	}
+
+	receipt := p.submitBlock(context.Background(), 10)
+	p.Pause()
+	if p.Mode() != ModePaused {
+		t.Errorf("Expected the poller to be paused, got mode %s", modeToString(p.Mode()))
+	}
+	select {
+	case <-receipt.Done():
+		if err := receipt.Err(); err != nil {
+			t.Errorf("Expected the block in flight to complete, got: %v", err)
+		}
+	default:
+		t.Error("Expected Pause() to wait for the block in flight")
+	}
+	if writes := driver.writes(10); writes != 1 {
+		t.Errorf("Expected block 10 to be written once, got %d", writes)
+	}
+}
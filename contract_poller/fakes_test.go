@@ -0,0 +1,169 @@
+package contract_poller
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/cache"
+	"github.com/coherentopensource/go-service-framework/metrics"
+	"github.com/coherentopensource/go-service-framework/pool"
+	"go.uber.org/zap"
+)
+
+// fakeDriver serves the contracts of a chain of blocks, recording which blocks are fetched and written; fetching a
+// block can be delayed
+type fakeDriver struct {
+	mu      *sync.Mutex
+	tip     uint64
+	delay   time.Duration
+	fetched map[uint64]int
+	written map[uint64]int
+}
+
+func newFakeDriver(tip uint64) *fakeDriver {
+	return &fakeDriver{mu: &sync.Mutex{}, tip: tip, fetched: map[uint64]int{}, written: map[uint64]int{}}
+}
+
+func (d *fakeDriver) fetches() map[uint64]int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := map[uint64]int{}
+	for block, count := range d.fetched {
+		out[block] = count
+	}
+	return out
+}
+
+func (d *fakeDriver) writes(block uint64) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.written[block]
+}
+
+func (d *fakeDriver) Blockchain() string {
+	return "test"
+}
+
+func (d *fakeDriver) GetChainTipNumber(ctx context.Context) (uint64, error) {
+	return d.tip, nil
+}
+
+func (d *fakeDriver) FetchSequence(blockNumber uint64) map[string]pool.Runner {
+	return map[string]pool.Runner{"addresses": func(ctx context.Context) (interface{}, error) {
+		d.mu.Lock()
+		d.fetched[blockNumber]++
+		delay := d.delay
+		d.mu.Unlock()
+		time.Sleep(delay)
+		return blockNumber, nil
+	}}
+}
+
+func (d *fakeDriver) Fetchers() map[string]pool.FeedTransformer {
+	return map[string]pool.FeedTransformer{"abi": func(res interface{}) pool.Runner {
+		return func(ctx context.Context) (interface{}, error) {
+			return res.(pool.ResultSet)["addresses"], nil
+		}
+	}}
+}
+
+func (d *fakeDriver) Accumulate(res interface{}) pool.Runner {
+	return func(ctx context.Context) (interface{}, error) {
+		return res.(pool.ResultSet)["abi"], nil
+	}
+}
+
+func (d *fakeDriver) Writers() []pool.FeedTransformer {
+	return []pool.FeedTransformer{func(res interface{}) pool.Runner {
+		return func(ctx context.Context) (interface{}, error) {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.written[res.(uint64)]++
+			return nil, nil
+		}
+	}}
+}
+
+// fakeCache holds cursors in memory
+type fakeCache struct {
+	mu      *sync.Mutex
+	cursors map[string]uint64
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{mu: &sync.Mutex{}, cursors: map[string]uint64{}}
+}
+
+func (c *fakeCache) cursor(key string) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cursor, ok := c.cursors[key]
+	return cursor, ok
+}
+
+func (c *fakeCache) GetCurrentBlockNumber(ctx context.Context, key string) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cursor, ok := c.cursors[key]
+	if !ok {
+		return 0, &cache.NotInRedisCacheError{}
+	}
+	return cursor, nil
+}
+
+func (c *fakeCache) SetCurrentBlockNumber(ctx context.Context, key string, block uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cursors[key] = block
+	return nil
+}
+
+// testConfig returns a config polling fast, without retries, so that tests neither sleep nor hang
+func testConfig() *Config {
+	return &Config{
+		Blockchain:   "test",
+		BatchSize:    4,
+		ReorgDepth:   2,
+		HttpRetries:  1,
+		SleepTime:    10 * time.Millisecond,
+		Tick:         time.Millisecond,
+		DrainTimeout: time.Second,
+	}
+}
+
+// newTestPoller builds a poller on running pools; the pools are stopped at the end of the test
+func newTestPoller(t *testing.T, cfg *Config, driver Driver, c Cache, opts ...opt) *Poller {
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+	noop, _ := metrics.NewNoopMetrics()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pools := []*pool.WorkerPool{}
+	for _, name := range []string{"fetch-address", "fetch", "accumulate", "write"} {
+		wp := pool.NewWorkerPool(name, pool.WithLogger(logger))
+		wp.Start(ctx)
+		pools = append(pools, wp)
+	}
+	t.Cleanup(func() {
+		cancel()
+		for _, wp := range pools {
+			wp.Stop()
+		}
+	})
+
+	opts = append([]opt{
+		WithAddressFetchPool(pools[0]),
+		WithFetchPool(pools[1]),
+		WithAccumulatePool(pools[2]),
+		WithWritePool(pools[3]),
+		WithCache(c),
+		WithLogger(logger),
+		WithMetrics(noop),
+	}, opts...)
+	return New(cfg, driver, opts...)
+}
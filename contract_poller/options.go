@@ -1,8 +1,10 @@
 package contract_poller
 
 import (
+	"github.com/coherentopensource/go-service-framework/circuitbreaker"
 	"github.com/coherentopensource/go-service-framework/pool"
 	"github.com/coherentopensource/go-service-framework/util"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type opt func(p *Poller)
@@ -44,3 +46,16 @@ func WithMetrics(metrics util.Metrics) opt {
 		p.metrics = metrics
 	}
 }
+func WithTracer(tracer trace.Tracer) opt {
+	return func(p *Poller) {
+		p.tracer = tracer
+	}
+}
+
+// WithCircuitBreaker specifies the breaker guarding calls to the node; the poller sleeps while it is open. Sharing it
+// with the driver's client, e.g. via circuitbreaker.Limiter(), lets failing fetches trip it too
+func WithCircuitBreaker(breaker *circuitbreaker.Breaker) opt {
+	return func(p *Poller) {
+		p.breaker = breaker
+	}
+}
@@ -2,8 +2,11 @@ package contract_poller
 
 import (
 	"context"
+	"github.com/coherentopensource/go-service-framework/circuitbreaker"
+	"github.com/coherentopensource/go-service-framework/pipeline"
 	"github.com/coherentopensource/go-service-framework/pool"
 	"github.com/coherentopensource/go-service-framework/util"
+	"go.opentelemetry.io/otel/trace"
 	"sync"
 	"time"
 )
@@ -39,6 +42,11 @@ type Poller struct {
 	writePool      *pool.WorkerPool
 	cancelFunc     context.CancelFunc
 	runCtx         context.Context
+	pipeline       *pipeline.Pipeline
+	tracer         trace.Tracer
+	breaker        *circuitbreaker.Breaker
+	//	maxBlock is the first block within reorg range, as of the last mode check; batches stop short of it
+	maxBlock uint64
 }
 
 // New constructs a new poller, given a config, a chain-specific driver, and a variadic array of options
@@ -57,9 +65,14 @@ func New(cfg *Config, driver Driver, opts ...opt) *Poller {
 	for _, opt := range opts {
 		opt(&p)
 	}
-	p.fetchPool.SetGroupInputFeed(p.getAddressPool.Results(), p.driver.Fetchers())
-	p.accumulatePool.SetInputFeed(p.fetchPool.Results(), p.driver.Accumulate)
-	p.writePool.SetInputFeed(p.accumulatePool.Results(), p.driver.Writers()...)
+	//	A config built by hand, rather than parsed from the environment, would otherwise time out every Pause() at once
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = pipeline.DefaultDrainTimeout
+	}
+
+	if err := p.buildPipeline(); err != nil {
+		p.logger.Fatalf("failed to build contract poller pipeline: %v", err)
+	}
 
 	return &p
 }
@@ -104,22 +117,18 @@ func (p *Poller) Start(ctx context.Context) error {
 				continue
 			case ModeBackfill:
 				//	If in ""backfill" mode, consume a batch of blocks and update the cursor
-				p.logger.Infof("Batch mode: start polling contracts at block %d with batch size %d", cursor, p.cfg.BatchSize)
-				wg := sync.WaitGroup{}
-				startIndex := cursor
-				for i := 0; i < p.cfg.BatchSize; i++ {
-					wg.Add(p.driverTaskLoad())
-					p.getAddressPool.PushGroup(p.driver.FetchSequence(cursor), &wg)
+				next, ok := p.runBatch(ctx, cursor)
+				if !ok {
+					continue
 				}
-				wg.Wait()
-				cursor = startIndex + uint64(p.cfg.BatchSize)
+				cursor = next
 			case ModeChaintip:
 				//	If in "chaintip" mode, pull the latest block, validate it, then consume it
 				p.logger.Infof("Chaintip mode: pulling block %d", cursor)
-				wg := sync.WaitGroup{}
-				wg.Add(p.driverTaskLoad())
-				p.getAddressPool.PushGroup(p.driver.FetchSequence(cursor), &wg)
-				wg.Wait()
+				receipt := p.submitBlock(ctx, cursor, pool.WithPriority(pool.PriorityHigh))
+				if !p.awaitBatch([]*pipeline.Receipt{receipt}) {
+					continue
+				}
 				cursor++
 			}
 
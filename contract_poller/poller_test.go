@@ -0,0 +1,47 @@
+package contract_poller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRunBatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("consecutive blocks", func(t *testing.T) {
+		driver := newFakeDriver(100)
+		c := newFakeCache()
+		p := newTestPoller(t, testConfig(), driver, c)
+		c.cursors[p.cacheKey()] = 10
+		p.mode = ModeReady
+		if _, err := p.setModeAndGetCursor(ctx); err != nil || p.Mode() != ModeBackfill {
+			t.Fatalf("Expected the poller to backfill, got mode %s: %v", modeToString(p.Mode()), err)
+		}
+
+		//	Each block of the batch is fetched once, from the cursor on
+		cursor, ok := p.runBatch(ctx, 10)
+		if !ok || cursor != 14 {
+			t.Errorf("Expected the cursor to advance to 14, got %d (complete: %t)", cursor, ok)
+		}
+		if fetched := fmt.Sprint(driver.fetches()); fetched != "map[10:1 11:1 12:1 13:1]" {
+			t.Errorf("Expected blocks 10 to 13 to be fetched once each, got %s", fetched)
+		}
+	})
+
+	t.Run("bounded by the reorg range", func(t *testing.T) {
+		driver := newFakeDriver(100)
+		p := newTestPoller(t, testConfig(), driver, newFakeCache())
+		p.maxBlock = 98
+
+		cursor, ok := p.runBatch(ctx, 96)
+		if !ok || cursor != 98 {
+			t.Errorf("Expected the cursor to stop at 98, got %d (complete: %t)", cursor, ok)
+		}
+		if fetched := fmt.Sprint(driver.fetches()); fetched != "map[96:1 97:1]" {
+			t.Errorf("Expected blocks 96 and 97 to be fetched once each, got %s", fetched)
+		}
+	})
+}
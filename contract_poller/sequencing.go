@@ -23,12 +23,20 @@ func (p *Poller) setModeAndGetCursor(ctx context.Context) (uint64, error) {
 		return cursor, nil
 	}
 
+	//	An open breaker means the node is failing; sleep rather than hammering it
+	if p.breakerOpen() {
+		p.setSleepMode()
+		p.logger.Warn("Circuit breaker is open; poller going to sleep")
+		return cursor, nil
+	}
+
 	chainTip, err := p.getRemoteChaintip(ctx)
 	if err != nil {
 		return 0, errors.Errorf("Error getting remote chaintip: %v", err)
 	}
 
 	maxBlock := chainTip - uint64(p.cfg.ReorgDepth)
+	p.maxBlock = maxBlock
 	distanceToMaxBlock := maxBlock - cursor
 
 	switch {
@@ -59,22 +67,21 @@ func (p *Poller) getCurrentChaintip(ctx context.Context) (uint64, error) {
 
 // setCurrentChaintip overwrites the current cached local chaintip value
 func (p *Poller) setCurrentChaintip(ctx context.Context, newTip uint64) error {
-	return retry.Exec(p.cfg.HttpRetries, func() error {
+	return retry.Do(ctx, p.retryPolicy(), func() error {
 		return p.cache.SetCurrentBlockNumber(ctx, p.cacheKey(), newTip)
-	}, nil)
+	})
 }
 
 // getRemoteChaintip pulls the remote chaintip value
 func (p *Poller) getRemoteChaintip(ctx context.Context) (uint64, error) {
 	var chainTip uint64
-	var err error
-	retry.Exec(p.cfg.HttpRetries, func() error {
-		chainTip, err = p.driver.GetChainTipNumber(ctx)
-		if err != nil {
+	err := retry.Do(ctx, p.retryPolicy(), func() error {
+		return p.guard(func() error {
+			var err error
+			chainTip, err = p.driver.GetChainTipNumber(ctx)
 			return err
-		}
-		return nil
-	}, nil)
+		})
+	})
 	return chainTip, err
 }
 
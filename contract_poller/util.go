@@ -1,19 +1,24 @@
 package contract_poller
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/coherentopensource/go-service-framework/circuitbreaker"
 	"github.com/coherentopensource/go-service-framework/constants"
+	"github.com/coherentopensource/go-service-framework/pipeline"
+	"github.com/coherentopensource/go-service-framework/pool"
+	"github.com/coherentopensource/go-service-framework/retry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"time"
 )
 
 func (p *Poller) cacheKey() string {
 	return fmt.Sprintf("contract_poller-%s-%s", p.driver.Blockchain(), constants.BlockKey)
 }
 
-func (p *Poller) driverTaskLoad() int {
-	//	count of address fetchers + fetchers + count of accumulators (always 1) + count of writers == number of queued jobs per block
-	return len(p.driver.FetchSequence(0)) + len(p.driver.Fetchers()) + 1 + len(p.driver.Writers())
-}
-
 func modeToString(mode int) string {
 	out := "unknown"
 	switch mode {
@@ -30,3 +35,131 @@ func modeToString(mode int) string {
 	}
 	return out
 }
+
+// buildPipeline declares the address fetch -> fetch -> accumulate -> write pipeline that every block flows through
+func (p *Poller) buildPipeline() error {
+	p.pipeline = pipeline.New(fmt.Sprintf("%s-contract-poller", p.driver.Blockchain()), pipeline.WithLogger(p.logger))
+	fetchAddresses := func(in interface{}) map[string]pool.Runner {
+		return p.driver.FetchSequence(in.(uint64))
+	}
+	if err := p.pipeline.AddGroupStage("fetch-address", p.getAddressPool, fetchAddresses); err != nil {
+		return err
+	}
+	if err := p.pipeline.AddGroupStage("fetch", p.fetchPool, pipeline.GroupOf(p.driver.Fetchers())); err != nil {
+		return err
+	}
+	if err := p.pipeline.AddStage("accumulate", p.accumulatePool, p.driver.Accumulate); err != nil {
+		return err
+	}
+	if err := p.pipeline.AddStage("write", p.writePool, p.driver.Writers()...); err != nil {
+		return err
+	}
+	if err := p.pipeline.Connect("fetch-address", "fetch"); err != nil {
+		return err
+	}
+	if err := p.pipeline.Connect("fetch", "accumulate"); err != nil {
+		return err
+	}
+	if err := p.pipeline.Connect("accumulate", "write"); err != nil {
+		return err
+	}
+	return p.pipeline.Build()
+}
+
+// submitBlock submits a block to the pipeline, under a span of its own if the poller has a tracer; the span ends once
+// every job of the block has completed
+func (p *Poller) submitBlock(ctx context.Context, block uint64, opts ...pool.PushOpt) *pipeline.Receipt {
+	opts = append(opts, pool.WithDescriptor(fmt.Sprint(block)))
+	if p.tracer == nil {
+		return p.pipeline.Submit(block, opts...)
+	}
+
+	ctx, span := p.tracer.Start(ctx, "poller.block", trace.WithAttributes(
+		attribute.Int64("block.number", int64(block)),
+		attribute.String("blockchain", string(p.driver.Blockchain())),
+		attribute.String("poller.mode", modeToString(p.mode)),
+	))
+	receipt := p.pipeline.Submit(block, append(opts, pool.WithTraceContext(ctx))...)
+	go func() {
+		if err := receipt.Wait(); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+	return receipt
+}
+
+// runBatch submits a batch of consecutive blocks from the cursor, stopping short of the reorg range, and returns the
+// cursor past the batch; returns false if the batch was interrupted, in which case the cursor must not advance
+func (p *Poller) runBatch(ctx context.Context, cursor uint64) (uint64, bool) {
+	batchSize := uint64(p.cfg.BatchSize)
+	if p.maxBlock > cursor && p.maxBlock-cursor < batchSize {
+		batchSize = p.maxBlock - cursor
+	}
+	p.logger.Infof("Batch mode: start polling contracts at block %d with batch size %d", cursor, batchSize)
+
+	receipts := make([]*pipeline.Receipt, 0, batchSize)
+	for i := uint64(0); i < batchSize; i++ {
+		receipts = append(receipts, p.submitBlock(ctx, cursor+i))
+	}
+	if !p.awaitBatch(receipts) {
+		return cursor, false
+	}
+	return cursor + batchSize, true
+}
+
+// awaitBatch waits for every block of a batch to complete, logging failed blocks; returns false if any block was
+// abandoned or turned away by Pause() or an open circuit breaker, in which case the cursor must not advance past the
+// batch
+func (p *Poller) awaitBatch(receipts []*pipeline.Receipt) bool {
+	complete, tripped := true, false
+	for _, receipt := range receipts {
+		err := receipt.Wait()
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, pool.ErrJobAbandoned) || errors.Is(err, pool.ErrPoolDraining) {
+			complete = false
+			continue
+		}
+		if errors.Is(err, circuitbreaker.ErrOpen) {
+			complete = false
+			tripped = true
+			continue
+		}
+		p.logger.Errorf("Error processing contracts of block: %v", err)
+	}
+	switch {
+	case tripped:
+		p.logger.Warn("Batch refused by open circuit breaker; the cursor will not advance")
+	case !complete:
+		p.logger.Warn("Batch interrupted by pause; the cursor will not advance")
+	}
+	return complete
+}
+
+// breakerOpen reports whether the circuit breaker, if any, is refusing calls to the node
+func (p *Poller) breakerOpen() bool {
+	return p.breaker != nil && p.breaker.State() == circuitbreaker.Open
+}
+
+// guard makes a call to the node through the circuit breaker, if any
+func (p *Poller) guard(fn retry.RunnerFunc) error {
+	if p.breaker == nil {
+		return fn()
+	}
+	return p.breaker.Execute(fn)
+}
+
+// retryPolicy retries calls to the node and the cache up to the configured number of times, backing off
+// exponentially; retries stop as soon as the poller does
+func (p *Poller) retryPolicy() retry.Policy {
+	policy := retry.Exponential(time.Second, 30*time.Second)
+	policy.MaxAttempts = p.cfg.HttpRetries
+	policy.OnRetry = func(attempt int, err error, delay time.Duration) {
+		p.logger.Warnf("Attempt %d failed, retrying in %v: %v", attempt, delay, err)
+		p.metrics.Incr(fmt.Sprintf("%s-poller-retries", p.cfg.Blockchain), []string{}, 1.0)
+	}
+	return policy
+}
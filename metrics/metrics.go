@@ -3,6 +3,7 @@ package metrics
 import (
 	"fmt"
 	"github.com/DataDog/datadog-go/v5/statsd"
+	"time"
 )
 
 // Metrics wrapper for customized statsd
@@ -45,6 +46,14 @@ func (s *Metrics) Gauge(name string, value float64, tags []string, rate float64)
 	return s.Client.Gauge(name, value, append([]string{fmt.Sprintf("env:%s", string(s.cfg.Env)), fmt.Sprintf("app:%s", s.cfg.AppName)}, tags...), rate)
 }
 
+func (s *Metrics) Histogram(name string, value float64, tags []string, rate float64) error {
+	return s.Client.Histogram(name, value, append([]string{fmt.Sprintf("env:%s", string(s.cfg.Env)), fmt.Sprintf("app:%s", s.cfg.AppName)}, tags...), rate)
+}
+
+func (s *Metrics) Timing(name string, value time.Duration, tags []string, rate float64) error {
+	return s.Client.Timing(name, value, append([]string{fmt.Sprintf("env:%s", string(s.cfg.Env)), fmt.Sprintf("app:%s", s.cfg.AppName)}, tags...), rate)
+}
+
 func (s *Metrics) Close() error {
 	return s.Close()
 }
@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/DataDog/datadog-go/v5/statsd"
 )
 
@@ -29,6 +31,14 @@ func (s *NoopMetrics) Gauge(name string, value float64, tags []string, rate floa
 	return nil
 }
 
+func (s *NoopMetrics) Histogram(name string, value float64, tags []string, rate float64) error {
+	return nil
+}
+
+func (s *NoopMetrics) Timing(name string, value time.Duration, tags []string, rate float64) error {
+	return nil
+}
+
 func (s *NoopMetrics) Close() error {
 	return nil
 }
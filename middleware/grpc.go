@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/coherentopensource/go-service-framework/rate_limiter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor applies the configured limiter, retries and timeout to unary gRPC calls. Calls failing
+// with Unavailable, ResourceExhausted or DeadlineExceeded (e.g. from the timeout) are retried, with the first two
+// reported to the limiter as throttling
+func UnaryClientInterceptor(opts ...opt) grpc.UnaryClientInterceptor {
+	cfg := newConfig(opts...)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		return cfg.run(ctx, func(ctx context.Context) error {
+			attemptCtx, cancel := cfg.attemptContext(ctx)
+			defer cancel()
+			return invoker(attemptCtx, method, req, reply, cc, callOpts...)
+		}, classifyGRPC)
+	}
+}
+
+// StreamClientInterceptor applies the configured limiter and retries to the establishment of gRPC streams; the
+// timeout doesn't apply, as it would bound the lifetime of the stream
+func StreamClientInterceptor(opts ...opt) grpc.StreamClientInterceptor {
+	cfg := newConfig(opts...)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		var stream grpc.ClientStream
+		err := cfg.run(ctx, func(ctx context.Context) error {
+			var err error
+			stream, err = streamer(ctx, desc, cc, method, callOpts...)
+			return err
+		}, classifyGRPC)
+		if err != nil {
+			return nil, err
+		}
+		return stream, nil
+	}
+}
+
+// classifyGRPC marks throttling statuses for the limiter; the caller still gets the original status error
+func classifyGRPC(err error) (error, bool) {
+	switch status.Code(err) {
+	case codes.OK:
+		return nil, false
+	case codes.ResourceExhausted:
+		return &rate_limiter.RetryAfterError{Err: rate_limiter.ErrRateLimited}, true
+	case codes.Unavailable:
+		return &rate_limiter.RetryAfterError{Err: rate_limiter.ErrOverloaded}, true
+	case codes.DeadlineExceeded:
+		return err, true
+	}
+	return err, false
+}
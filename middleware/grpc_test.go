@@ -0,0 +1,96 @@
+package middleware_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coherentopensource/go-service-framework/middleware"
+	"github.com/coherentopensource/go-service-framework/rate_limiter"
+	"github.com/coherentopensource/go-service-framework/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	noSleep := retry.Constant(0)
+	noSleep.MaxAttempts = 3
+	ctx := context.Background()
+
+	//	invoker fails with the given codes in turn, then succeeds
+	invoker := func(calls *int, failures ...codes.Code) grpc.UnaryInvoker {
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			*calls++
+			if *calls <= len(failures) {
+				return status.Error(failures[*calls-1], "failed")
+			}
+			return nil
+		}
+	}
+
+	t.Run("retries unavailable", func(t *testing.T) {
+		calls := 0
+		interceptor := middleware.UnaryClientInterceptor(middleware.WithRetries(noSleep))
+		if err := interceptor(ctx, "/test", nil, nil, nil, invoker(&calls, codes.Unavailable, codes.Unavailable)); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if calls != 3 {
+			t.Errorf("Expected 3 attempts, got %d", calls)
+		}
+	})
+
+	t.Run("no retry on other codes", func(t *testing.T) {
+		calls := 0
+		interceptor := middleware.UnaryClientInterceptor(middleware.WithRetries(noSleep))
+		err := interceptor(ctx, "/test", nil, nil, nil, invoker(&calls, codes.InvalidArgument))
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected the original InvalidArgument status, got: %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("Expected a single attempt, got %d", calls)
+		}
+	})
+
+	t.Run("resource exhausted throttles", func(t *testing.T) {
+		calls := 0
+		limiter := rate_limiter.NewAdaptiveLimiter(rate_limiter.AdaptiveConfig{InitialRate: 100, MinRate: 1, MaxRate: 100})
+		interceptor := middleware.UnaryClientInterceptor(middleware.WithLimiter(limiter), middleware.WithRetries(noSleep))
+		if err := interceptor(ctx, "/test", nil, nil, nil, invoker(&calls, codes.ResourceExhausted)); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if calls != 2 {
+			t.Errorf("Expected 2 attempts, got %d", calls)
+		}
+		if limiter.Rate() >= 100 {
+			t.Errorf("Expected the throttled call to lower the rate, got %v", limiter.Rate())
+		}
+	})
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	noSleep := retry.Constant(0)
+	noSleep.MaxAttempts = 3
+	ctx := context.Background()
+
+	//	Establishing the stream is retried while the server is unavailable, but not on other codes
+	for name, code := range map[string]codes.Code{"unavailable": codes.Unavailable, "invalid argument": codes.InvalidArgument} {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				calls++
+				return nil, status.Error(code, "failed")
+			}
+			interceptor := middleware.StreamClientInterceptor(middleware.WithRetries(noSleep))
+			if _, err := interceptor(ctx, &grpc.StreamDesc{}, nil, "/test", streamer); status.Code(err) != code {
+				t.Errorf("Expected the original %s status, got: %v", code, err)
+			}
+			expected := 1
+			if code == codes.Unavailable {
+				expected = noSleep.MaxAttempts
+			}
+			if calls != expected {
+				t.Errorf("Expected %d attempts, got %d", expected, calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/rate_limiter"
+)
+
+// statusError reports a response status worth retrying; it never reaches the caller, who gets the response itself
+type statusError struct {
+	code       int
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("http status %d", e.code)
+}
+
+type roundTripper struct {
+	next http.RoundTripper
+	cfg  *config
+}
+
+// WithRetryAllMethods lets RoundTripper() retry requests whatever their method, e.g. for JSON-RPC, where reads are
+// POSTed; only use it when every call made through the client is safe to repeat
+func WithRetryAllMethods() opt {
+	return func(c *config) {
+		c.retryAllMethods = true
+	}
+}
+
+// RoundTripper wraps an http.RoundTripper (http.DefaultTransport if nil) with the configured limiter, retries and
+// timeout. Network errors and 429, 500, 502, 503 and 504 responses are retried, with Retry-After hints passed on to
+// the limiter; request bodies are buffered so that they can be replayed. Only idempotent requests are retried, as
+// net/http defines them, unless WithRetryAllMethods() is given, e.g. for the http.Client of a JSON-RPC driver. Once
+// attempts are exhausted, the last response is returned as is
+func RoundTripper(next http.RoundTripper, opts ...opt) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &roundTripper{next: next, cfg: newConfig(opts...)}
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	getBody, err := replayableBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	classify := classifyHTTP
+	if !rt.cfg.retryAllMethods && !isIdempotent(req) {
+		classify = func(err error) (error, bool) {
+			classified, _ := classifyHTTP(err)
+			return classified, false
+		}
+	}
+
+	var resp *http.Response
+	err = rt.cfg.run(req.Context(), func(ctx context.Context) error {
+		//	a response superseded by a retry is discarded
+		if resp != nil {
+			discard(resp)
+			resp = nil
+		}
+
+		attemptCtx, cancel := rt.cfg.attemptContext(ctx)
+		attemptReq := req.Clone(attemptCtx)
+		body, err := getBody()
+		if err != nil {
+			cancel()
+			return err
+		}
+		attemptReq.Body = body
+		res, err := rt.next.RoundTrip(attemptReq)
+		if err != nil {
+			cancel()
+			return err
+		}
+		//	the timeout covers reading the body too, so the attempt's context lives until the body is closed
+		res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+		resp = res
+		return checkStatus(res)
+	}, classify)
+
+	var statusErr *statusError
+	if err == nil || errors.As(err, &statusErr) {
+		return resp, nil
+	}
+	if resp != nil {
+		discard(resp)
+	}
+	return nil, err
+}
+
+// isIdempotent reports whether a request is safe to repeat: its method is idempotent, or it carries an idempotency
+// key, as net/http decides when retrying requests on a broken connection
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, hasKey := req.Header["Idempotency-Key"]
+	_, hasXKey := req.Header["X-Idempotency-Key"]
+	return hasKey || hasXKey
+}
+
+// checkStatus turns retryable statuses into errors
+func checkStatus(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable,
+		http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return &statusError{
+			code:       resp.StatusCode,
+			retryAfter: rate_limiter.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	return nil
+}
+
+// classifyHTTP marks throttling statuses for the limiter; every error reaching it is worth retrying
+func classifyHTTP(err error) (error, bool) {
+	var statusErr *statusError
+	if !errors.As(err, &statusErr) {
+		return err, err != nil
+	}
+	switch statusErr.code {
+	case http.StatusTooManyRequests:
+		return &rate_limiter.RetryAfterError{Err: fmt.Errorf("%w: %w", err, rate_limiter.ErrRateLimited), RetryAfter: statusErr.retryAfter}, true
+	case http.StatusServiceUnavailable:
+		return &rate_limiter.RetryAfterError{Err: fmt.Errorf("%w: %w", err, rate_limiter.ErrOverloaded), RetryAfter: statusErr.retryAfter}, true
+	}
+	return err, true
+}
+
+// replayableBody returns a function producing a fresh copy of the request body for every attempt; the original body
+// is closed
+func replayableBody(req *http.Request) (func() (io.ReadCloser, error), error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return func() (io.ReadCloser, error) { return req.Body, nil }, nil
+	}
+	if req.GetBody != nil {
+		//	every attempt reads a fresh copy, so the original body is closed now, as RoundTrip must
+		req.Body.Close()
+		return req.GetBody, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	return func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }, nil
+}
+
+// discard drains and closes a response body, so that its connection can be reused
+func discard(resp *http.Response) {
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
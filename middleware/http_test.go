@@ -0,0 +1,123 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/middleware"
+	"github.com/coherentopensource/go-service-framework/rate_limiter"
+	"github.com/coherentopensource/go-service-framework/retry"
+)
+
+func TestRoundTripper(t *testing.T) {
+	//	The server throttles the first call, fails the second, and echoes the body of the third
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			io.Copy(w, r.Body)
+		}
+	}))
+	defer server.Close()
+
+	limiter := rate_limiter.NewAdaptiveLimiter(rate_limiter.AdaptiveConfig{InitialRate: 100, MinRate: 1, MaxRate: 100})
+	noSleep := retry.Constant(0)
+	noSleep.MaxAttempts = 3
+	client := &http.Client{Transport: middleware.RoundTripper(nil,
+		middleware.WithLimiter(limiter),
+		middleware.WithRetries(noSleep),
+		middleware.WithTimeout(time.Second),
+		middleware.WithRetryAllMethods(),
+	)}
+
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"method":"eth_blockNumber"}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != `{"method":"eth_blockNumber"}` {
+		t.Errorf("Expected the replayed body to be echoed, got %d: %s", resp.StatusCode, body)
+	}
+	if calls := atomic.LoadInt32(&calls); calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+	if limiter.Rate() >= 100 {
+		t.Errorf("Expected the throttled call to lower the rate, got %v", limiter.Rate())
+	}
+
+	//	Once attempts are exhausted, the last response is returned as is
+	atomic.StoreInt32(&calls, 0)
+	noSleep.MaxAttempts = 2
+	client.Transport = middleware.RoundTripper(nil, middleware.WithRetries(noSleep))
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected the last response to be returned, got %d", resp.StatusCode)
+	}
+
+	//	Requests that aren't idempotent are not retried by default
+	atomic.StoreInt32(&calls, 0)
+	resp, err = client.Post(server.URL, "application/json", strings.NewReader(`{"method":"eth_sendRawTransaction"}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if calls := atomic.LoadInt32(&calls); calls != 1 || resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected a single attempt of the POST, got %d attempts and status %d", calls, resp.StatusCode)
+	}
+}
+
+// closeTracker records whether a request body was closed
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestRoundTripperClosesBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+
+	//	A request that can produce copies of its body still has its original body closed
+	body := &closeTracker{Reader: strings.NewReader("payload")}
+	req, err := http.NewRequest(http.MethodPut, server.URL, body)
+	if err != nil {
+		t.Fatalf("Error building request: %v", err)
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("payload")), nil
+	}
+
+	resp, err := middleware.RoundTripper(nil).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	echoed, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(echoed) != "payload" {
+		t.Errorf("Expected the body to be sent, got %s", echoed)
+	}
+	if !body.closed {
+		t.Error("Expected the original request body to be closed")
+	}
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/rate_limiter"
+	"github.com/coherentopensource/go-service-framework/retry"
+	"golang.org/x/time/rate"
+)
+
+// config holds the policies applied to every call made through a middleware
+type config struct {
+	limiter rate_limiter.Limiter
+	retry   retry.Policy
+	timeout time.Duration
+	//	retryAllMethods lets RoundTripper() retry requests that aren't idempotent
+	retryAllMethods bool
+}
+
+type opt func(c *config)
+
+// WithLimiter paces calls with the supplied limiter; limiters that adapt to the outcome of calls, such as
+// rate_limiter.AdaptiveLimiter, are told about throttling responses
+func WithLimiter(limiter rate_limiter.Limiter) opt {
+	return func(c *config) {
+		c.limiter = limiter
+	}
+}
+
+// WithRetries retries calls failing with a retryable error, as the supplied policy dictates; by default, calls are
+// made once
+func WithRetries(policy retry.Policy) opt {
+	return func(c *config) {
+		c.retry = policy
+	}
+}
+
+// WithTimeout bounds every attempt of a call, excluding the time spent waiting for the limiter
+func WithTimeout(timeout time.Duration) opt {
+	return func(c *config) {
+		c.timeout = timeout
+	}
+}
+
+func newConfig(opts ...opt) *config {
+	c := config{
+		limiter: rate.NewLimiter(rate.Inf, 1),
+		retry:   retry.Policy{MaxAttempts: 1},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return &c
+}
+
+// attemptContext derives the context of a single attempt, bounded by the timeout if any
+func (c *config) attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout > 0 {
+		return context.WithTimeout(ctx, c.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// classifier translates the error of an attempt for the limiter, e.g. into rate_limiter.ErrRateLimited, and decides
+// whether it is worth retrying
+type classifier func(err error) (classified error, retryable bool)
+
+// run makes attempts of fn until one succeeds, fails with an error that isn't worth retrying, or the attempts are
+// exhausted, returning the error of the last attempt as fn reported it
+func (c *config) run(ctx context.Context, fn func(ctx context.Context) error, classify classifier) error {
+	client := rate_limiter.NewClientWithLimiter(0, c.limiter)
+	var lastErr error
+	retry.Do(ctx, c.retry, func() error {
+		var retryable, ran bool
+		err := client.Exec(ctx, func() error {
+			ran = true
+			lastErr = fn(ctx)
+			var classified error
+			classified, retryable = classify(lastErr)
+			return classified
+		})
+		if !ran {
+			//	the limiter refused to wait, e.g. as ctx is done
+			lastErr = err
+			return retry.Permanent(err)
+		}
+		if err != nil && !retryable {
+			return retry.Permanent(err)
+		}
+		return err
+	})
+	return lastErr
+}
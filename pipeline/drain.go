@@ -0,0 +1,86 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/pool"
+)
+
+const (
+	drainPollInterval = 10 * time.Millisecond
+	// DefaultDrainTimeout is the deadline pollers give a drain on Pause(), unless configured otherwise
+	DefaultDrainTimeout = 30 * time.Second
+)
+
+// DrainReport summarizes a call to Drain()
+type DrainReport struct {
+	//	Stages holds the drain report of each stage's pool, for stages that had to be drained because the deadline
+	//	passed; it is empty if every item completed in time
+	Stages map[string]*pool.DrainReport
+	//	InFlight is the number of items that had not completed when the drain deadline passed; their receipts still
+	//	complete, with an error if any of their jobs were abandoned
+	InFlight int
+}
+
+// Complete reports whether the drain finished every item before its deadline
+func (r *DrainReport) Complete() bool {
+	return r.InFlight == 0
+}
+
+// Abandoned counts the jobs abandoned across all stages
+func (r *DrainReport) Abandoned() int {
+	count := 0
+	for _, report := range r.Stages {
+		count += len(report.Abandoned)
+	}
+	return count
+}
+
+// Drain stops the pipeline from accepting new items, then waits for every submitted item to complete; if ctx ends
+// first, the pools of the stages are drained upstream first with the expired context, abandoning their queued jobs
+// so that the receipts of the items involved complete with pool.ErrJobAbandoned. The pipeline resumes accepting
+// items once Drain returns
+func (p *Pipeline) Drain(ctx context.Context) *DrainReport {
+	if !p.built {
+		return &DrainReport{}
+	}
+	p.drainMu.Lock()
+	p.draining = true
+	p.drainMu.Unlock()
+	defer func() {
+		p.drainMu.Lock()
+		p.draining = false
+		p.drainMu.Unlock()
+	}()
+
+	report := &DrainReport{Stages: map[string]*pool.DrainReport{}}
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for p.inFlight() > 0 {
+		select {
+		case <-ctx.Done():
+			return p.abandon(ctx, report)
+		case <-ticker.C:
+		}
+	}
+
+	p.logger.Infof("Pipeline [%s] drained", p.id)
+	return report
+}
+
+// abandon drains the pools of every stage, upstream first, with an expired context, so that their queued jobs are
+// abandoned; a pool backing several stages is drained once, and reported under the first of them
+func (p *Pipeline) abandon(ctx context.Context, report *DrainReport) *DrainReport {
+	drained := map[*pool.WorkerPool]bool{}
+	for _, s := range p.order {
+		if drained[s.pool] {
+			continue
+		}
+		drained[s.pool] = true
+		report.Stages[s.name] = s.pool.Drain(ctx)
+	}
+	report.InFlight = p.inFlight()
+	p.logger.Warnf("Drain of pipeline [%s] timed out; %d jobs abandoned, %d items still in flight", p.id, report.Abandoned(), report.InFlight)
+	return report
+}
@@ -0,0 +1,21 @@
+package pipeline
+
+func (p *Pipeline) inFlight() int {
+	p.countersMu.Lock()
+	defer p.countersMu.Unlock()
+	return p.countInFlight
+}
+
+// Insights reports item counts for the pipeline as a whole; per-stage counts are available from the Insights() of
+// each stage's pool
+func (p *Pipeline) Insights() map[string]int {
+	p.countersMu.Lock()
+	defer p.countersMu.Unlock()
+
+	return map[string]int{
+		"submitted": p.countSubmitted,
+		"inFlight":  p.countInFlight,
+		"completed": p.countCompleted,
+		"failed":    p.countFailed,
+	}
+}
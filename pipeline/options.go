@@ -0,0 +1,12 @@
+package pipeline
+
+import "github.com/coherentopensource/go-service-framework/util"
+
+type opt func(p *Pipeline)
+
+// WithLogger overrides the default logger
+func WithLogger(logger util.Logger) opt {
+	return func(p *Pipeline) {
+		p.logger = logger
+	}
+}
@@ -0,0 +1,328 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/coherentopensource/go-service-framework/pool"
+	"github.com/coherentopensource/go-service-framework/util"
+)
+
+var (
+	// ErrNotBuilt is returned for items submitted to a pipeline before Build() succeeded
+	ErrNotBuilt = errors.New("pipeline is not built")
+)
+
+// Pipeline declares a DAG of stages, each backed by a dedicated WorkerPool, and tracks the completion of every item
+// submitted to it across all stages; callers never compute WaitGroup counts by hand. The pipeline routes results
+// between stages itself, by way of a per-push emitter, so a pool may back several stages (or pipelines) and still
+// consume input feeds of its own; the lifecycle (Start/Stop) of the pools remains the responsibility of the caller
+type Pipeline struct {
+	id             string
+	logger         util.Logger
+	stages         map[string]*stage
+	order          []*stage
+	roots          []*stage
+	built          bool
+	items          map[*sync.WaitGroup]*item
+	itemsMu        *sync.Mutex
+	draining       bool
+	drainMu        *sync.RWMutex
+	countSubmitted int
+	countCompleted int
+	countFailed    int
+	countInFlight  int
+	countersMu     *sync.Mutex
+}
+
+// New instantiates an empty pipeline; declare its stages with AddStage()/AddGroupStage(), link them with Connect(),
+// then call Build() before submitting items
+func New(id string, opts ...opt) *Pipeline {
+	p := Pipeline{
+		id:         id,
+		stages:     map[string]*stage{},
+		items:      map[*sync.WaitGroup]*item{},
+		itemsMu:    &sync.Mutex{},
+		drainMu:    &sync.RWMutex{},
+		countersMu: &sync.Mutex{},
+	}
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	return &p
+}
+
+// AddStage declares a stage whose pool runs one job per transformer for each input it receives; each successful job
+// forwards its result to the downstream stages
+func (p *Pipeline) AddStage(name string, wp *pool.WorkerPool, transformers ...pool.FeedTransformer) error {
+	if len(transformers) == 0 {
+		return fmt.Errorf("stage [%s] of pipeline [%s] has no transformers", name, p.id)
+	}
+	return p.addStage(&stage{name: name, pool: wp, transformers: transformers})
+}
+
+// AddGroupStage declares a stage whose pool runs the members of a group for each input it receives; the group
+// forwards a single ResultSet (or PartialResultSet, per the group policy) to the downstream stages
+func (p *Pipeline) AddGroupStage(name string, wp *pool.WorkerPool, group GroupTransformer) error {
+	if group == nil {
+		return fmt.Errorf("stage [%s] of pipeline [%s] has no group transformer", name, p.id)
+	}
+	return p.addStage(&stage{name: name, pool: wp, group: group})
+}
+
+func (p *Pipeline) addStage(s *stage) error {
+	if p.built {
+		return fmt.Errorf("pipeline [%s] is already built", p.id)
+	}
+	if s.pool == nil {
+		return fmt.Errorf("stage [%s] of pipeline [%s] has no worker pool", s.name, p.id)
+	}
+	if _, ok := p.stages[s.name]; ok {
+		return fmt.Errorf("stage [%s] is already defined in pipeline [%s]", s.name, p.id)
+	}
+	p.stages[s.name] = s
+	return nil
+}
+
+// Connect forwards the results of one stage to one or more downstream stages; connecting several stages to the same
+// downstream stage makes it a fan-in stage, which receives a ResultSet keyed by upstream stage name once every
+// upstream stage has produced its result for an item
+func (p *Pipeline) Connect(from string, to ...string) error {
+	if p.built {
+		return fmt.Errorf("pipeline [%s] is already built", p.id)
+	}
+	up, ok := p.stages[from]
+	if !ok {
+		return fmt.Errorf("unknown stage [%s] in pipeline [%s]", from, p.id)
+	}
+	for _, name := range to {
+		down, ok := p.stages[name]
+		if !ok {
+			return fmt.Errorf("unknown stage [%s] in pipeline [%s]", name, p.id)
+		}
+		up.downstream = append(up.downstream, down)
+		down.upstream = append(down.upstream, up)
+	}
+	return nil
+}
+
+// Build validates the declared DAG and attaches the pipeline to the pools of its stages
+func (p *Pipeline) Build() error {
+	if p.built {
+		return fmt.Errorf("pipeline [%s] is already built", p.id)
+	}
+	if p.logger == nil {
+		return errors.New("Logger not configured")
+	}
+	if len(p.stages) == 0 {
+		return fmt.Errorf("pipeline [%s] has no stages", p.id)
+	}
+
+	//	a fan-in stage joins exactly one result per upstream stage and item
+	for _, s := range p.stages {
+		if len(s.upstream) < 2 {
+			continue
+		}
+		for _, up := range s.upstream {
+			if up.emissionsPerItem() != 1 {
+				return fmt.Errorf("stage [%s] of pipeline [%s] feeds fan-in stage [%s] but emits %d results per item", up.name, p.id, s.name, up.emissionsPerItem())
+			}
+		}
+	}
+
+	order, err := p.sort()
+	if err != nil {
+		return err
+	}
+	p.order = order
+	for _, s := range order {
+		if len(s.upstream) == 0 {
+			p.roots = append(p.roots, s)
+		}
+		s.emitter = p.emitterFor(s)
+	}
+	p.built = true
+
+	return nil
+}
+
+// sort orders the stages topologically, upstream first, failing if the declared graph has a cycle
+func (p *Pipeline) sort() ([]*stage, error) {
+	inDegree := make(map[*stage]int, len(p.stages))
+	var queue []*stage
+	for _, s := range p.stages {
+		inDegree[s] = len(s.upstream)
+		if len(s.upstream) == 0 {
+			queue = append(queue, s)
+		}
+	}
+
+	order := make([]*stage, 0, len(p.stages))
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		order = append(order, s)
+		for _, down := range s.downstream {
+			inDegree[down]--
+			if inDegree[down] == 0 {
+				queue = append(queue, down)
+			}
+		}
+	}
+	if len(order) != len(p.stages) {
+		return nil, fmt.Errorf("pipeline [%s] has a cycle", p.id)
+	}
+	return order, nil
+}
+
+// Submit feeds an input to every root stage of the pipeline, returning a receipt that completes once every job
+// spawned for the input, in every stage, has finished; push options (e.g. pool.WithPriority) apply to every job
+// of the item
+func (p *Pipeline) Submit(input interface{}, opts ...pool.PushOpt) *Receipt {
+	it := newItem(opts)
+	if !p.built {
+		it.receipt.fail(p.id, ErrNotBuilt)
+		close(it.receipt.doneCh)
+		return it.receipt
+	}
+
+	p.drainMu.RLock()
+	defer p.drainMu.RUnlock()
+	if p.draining {
+		it.receipt.fail(p.id, pool.ErrPoolDraining)
+		close(it.receipt.doneCh)
+		return it.receipt
+	}
+
+	p.itemsMu.Lock()
+	p.items[it.wg] = it
+	p.itemsMu.Unlock()
+	p.countersMu.Lock()
+	p.countSubmitted++
+	p.countInFlight++
+	p.countersMu.Unlock()
+
+	//	hold the receipt open until the input has been dispatched to every root stage
+	it.wg.Add(1)
+	for _, s := range p.roots {
+		p.dispatch(it, s, input)
+	}
+	it.wg.Done()
+
+	go p.complete(it)
+	return it.receipt
+}
+
+// complete waits for every job of an item, then releases its receipt
+func (p *Pipeline) complete(it *item) {
+	it.wg.Wait()
+
+	p.itemsMu.Lock()
+	delete(p.items, it.wg)
+	p.itemsMu.Unlock()
+
+	failed := it.receipt.Err() != nil
+	p.countersMu.Lock()
+	p.countInFlight--
+	p.countCompleted++
+	if failed {
+		p.countFailed++
+	}
+	p.countersMu.Unlock()
+
+	close(it.receipt.doneCh)
+}
+
+// dispatch pushes the jobs of a stage for an input, adding them to the item's receipt before they are pushed
+func (p *Pipeline) dispatch(it *item, s *stage, input interface{}) {
+	if s.group != nil {
+		members := p.group(s, input)
+		//	an empty group has nothing to run, so its (empty) result set is forwarded straight away
+		if len(members) == 0 {
+			p.route(it, s, pool.ResultSet{})
+			return
+		}
+		it.wg.Add(len(members))
+		s.pool.PushGroup(members, it.wg, it.pushOpts(s)...)
+		return
+	}
+
+	for _, transformer := range s.transformers {
+		fn := p.transform(s, transformer, input)
+		it.wg.Add(1)
+		s.pool.PushJob(fn, it.wg, it.pushOpts(s)...)
+	}
+}
+
+// emitterFor builds the emitter of a stage, which records failures on the item's receipt and forwards successful
+// results to the downstream stages
+func (p *Pipeline) emitterFor(s *stage) pool.Emitter {
+	return func(e pool.Emission) {
+		p.itemsMu.Lock()
+		it, ok := p.items[e.Receipt]
+		p.itemsMu.Unlock()
+		if !ok {
+			p.logger.Warnf("Stage [%s] of pipeline [%s] emitted a result for an unknown item", s.name, p.id)
+			return
+		}
+
+		if e.Err != nil {
+			it.receipt.fail(s.name, e.Err)
+			return
+		}
+		p.route(it, s, e.Payload)
+	}
+}
+
+// route forwards the result of a stage to its downstream stages; fan-in stages are only dispatched once the results
+// of all of their upstream stages have been joined
+func (p *Pipeline) route(it *item, s *stage, payload interface{}) {
+	for _, down := range s.downstream {
+		if len(down.upstream) < 2 {
+			p.dispatch(it, down, payload)
+			continue
+		}
+		if joined, ok := it.join(down, s, payload); ok {
+			p.dispatch(it, down, joined)
+		}
+	}
+}
+
+// transform applies a FeedTransformer to an input; if the transformer panics, the resulting Runner reports the
+// panic as a pool.PanicError so that the item still completes
+func (p *Pipeline) transform(s *stage, transformer pool.FeedTransformer, input interface{}) (fn pool.Runner) {
+	defer func() {
+		if val := recover(); val != nil {
+			err := p.recovered(s, val)
+			fn = func(ctx context.Context) (interface{}, error) {
+				return nil, err
+			}
+		}
+	}()
+	return transformer(input)
+}
+
+// group applies the GroupTransformer of a stage to an input, with the same panic handling as transform()
+func (p *Pipeline) group(s *stage, input interface{}) (members map[string]pool.Runner) {
+	defer func() {
+		if val := recover(); val != nil {
+			err := p.recovered(s, val)
+			members = map[string]pool.Runner{
+				"group transformer": func(ctx context.Context) (interface{}, error) {
+					return nil, err
+				},
+			}
+		}
+	}()
+	return s.group(input)
+}
+
+func (p *Pipeline) recovered(s *stage, val interface{}) *pool.PanicError {
+	err := &pool.PanicError{JobID: fmt.Sprintf("%s transformer", s.name), Value: val, Stack: debug.Stack()}
+	p.logger.Errorf("Recovered from panic in transformer of stage [%s] of pipeline [%s]: %v\n%s", s.name, p.id, val, err.Stack)
+	return err
+}
@@ -0,0 +1,244 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"github.com/coherentopensource/go-service-framework/pipeline"
+	"github.com/coherentopensource/go-service-framework/pool"
+	"go.uber.org/zap"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPipeline(t *testing.T) {
+	//	Instantiate logger
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+
+	//	Fetch a group per block, then fan out to two accumulators that fan back in to a single writer
+	fetchPool := pool.NewWorkerPool("fetch", pool.WithLogger(logger))
+	sumPool := pool.NewWorkerPool("sum", pool.WithLogger(logger))
+	productPool := pool.NewWorkerPool("product", pool.WithLogger(logger))
+	writePool := pool.NewWorkerPool("write", pool.WithLogger(logger))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, wp := range []*pool.WorkerPool{fetchPool, sumPool, productPool, writePool} {
+		wp.Start(ctx)
+		defer wp.Stop()
+	}
+
+	constant := func(val int) pool.Runner {
+		return func(ctx context.Context) (interface{}, error) {
+			time.Sleep(10 * time.Millisecond)
+			return val, nil
+		}
+	}
+
+	written := map[int][2]int{}
+	writtenMu := &sync.Mutex{}
+
+	p := pipeline.New("test", pipeline.WithLogger(logger))
+	mustNot := func(err error) {
+		if err != nil {
+			t.Fatalf("Error declaring pipeline: %v", err)
+		}
+	}
+	mustNot(p.AddGroupStage("fetch", fetchPool, func(in interface{}) map[string]pool.Runner {
+		block := in.(int)
+		return map[string]pool.Runner{"a": constant(block), "b": constant(block + 1)}
+	}))
+	mustNot(p.AddStage("sum", sumPool, func(in interface{}) pool.Runner {
+		set := in.(pool.ResultSet)
+		return constant(set["a"].(int) + set["b"].(int))
+	}))
+	mustNot(p.AddStage("product", productPool, func(in interface{}) pool.Runner {
+		set := in.(pool.ResultSet)
+		if set["a"].(int) == 3 {
+			panic("unlucky block")
+		}
+		return constant(set["a"].(int) * set["b"].(int))
+	}))
+	mustNot(p.AddStage("write", writePool, func(in interface{}) pool.Runner {
+		return func(ctx context.Context) (interface{}, error) {
+			joined := in.(pool.ResultSet)
+			sum, product := joined["sum"].(int), joined["product"].(int)
+			if sum == 9 {
+				return nil, errors.New("write failed")
+			}
+			writtenMu.Lock()
+			defer writtenMu.Unlock()
+			written[sum] = [2]int{sum, product}
+			return nil, nil
+		}
+	}))
+	mustNot(p.Connect("fetch", "sum", "product"))
+	mustNot(p.Connect("sum", "write"))
+	mustNot(p.Connect("product", "write"))
+	mustNot(p.Build())
+
+	//	Block 3 panics in a transformer and block 4 fails to write; every other block is written
+	receipts := map[int]*pipeline.Receipt{}
+	for block := 1; block <= 6; block++ {
+		receipts[block] = p.Submit(block)
+	}
+	for block, receipt := range receipts {
+		err := receipt.Wait()
+		switch block {
+		case 3:
+			var panicErr *pool.PanicError
+			if !errors.As(err, &panicErr) {
+				t.Errorf("Expected a panic error for block 3, got: %v", err)
+			}
+		case 4:
+			if err == nil {
+				t.Error("Expected a write error for block 4")
+			}
+		default:
+			if err != nil {
+				t.Errorf("Unexpected error for block %d: %v", block, err)
+			}
+			sum := 2*block + 1
+			writtenMu.Lock()
+			got := written[sum]
+			writtenMu.Unlock()
+			if got != [2]int{sum, block * (block + 1)} {
+				t.Errorf("Unexpected write for block %d: %v", block, got)
+			}
+		}
+	}
+
+	insights := p.Insights()
+	if insights["completed"] != 6 || insights["failed"] != 2 || insights["inFlight"] != 0 {
+		t.Errorf("Unexpected insights: %v", insights)
+	}
+
+	//	A cyclic declaration is refused
+	cyclic := pipeline.New("cyclic", pipeline.WithLogger(logger))
+	mustNot(cyclic.AddStage("a", pool.NewWorkerPool("a", pool.WithLogger(logger)), func(in interface{}) pool.Runner { return constant(0) }))
+	mustNot(cyclic.AddStage("b", pool.NewWorkerPool("b", pool.WithLogger(logger)), func(in interface{}) pool.Runner { return constant(0) }))
+	mustNot(cyclic.Connect("a", "b"))
+	mustNot(cyclic.Connect("b", "a"))
+	if err := cyclic.Build(); err == nil {
+		t.Error("Expected a cyclic pipeline to fail to build")
+	}
+}
+
+func TestPipelineDrain(t *testing.T) {
+	//	Instantiate logger
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	//	setup builds a fetch stage, running a single job at a time, that feeds a write stage
+	setup := func(t *testing.T, fetch pool.Runner) *pipeline.Pipeline {
+		fetchPool := pool.NewWorkerPool("fetch", pool.WithLogger(logger), pool.WithBandwidth(1), pool.WithBufferSize(10))
+		writePool := pool.NewWorkerPool("write", pool.WithLogger(logger))
+		for _, wp := range []*pool.WorkerPool{fetchPool, writePool} {
+			wp.Start(ctx)
+			t.Cleanup(wp.Stop)
+		}
+
+		p := pipeline.New("test", pipeline.WithLogger(logger))
+		if err := p.AddStage("fetch", fetchPool, func(in interface{}) pool.Runner { return fetch }); err != nil {
+			t.Fatalf("Error declaring pipeline: %v", err)
+		}
+		if err := p.AddStage("write", writePool, func(in interface{}) pool.Runner {
+			return func(ctx context.Context) (interface{}, error) { return nil, nil }
+		}); err != nil {
+			t.Fatalf("Error declaring pipeline: %v", err)
+		}
+		if err := p.Connect("fetch", "write"); err != nil {
+			t.Fatalf("Error declaring pipeline: %v", err)
+		}
+		if err := p.Build(); err != nil {
+			t.Fatalf("Error building pipeline: %v", err)
+		}
+		return p
+	}
+
+	t.Run("complete", func(t *testing.T) {
+		p := setup(t, func(ctx context.Context) (interface{}, error) {
+			time.Sleep(20 * time.Millisecond)
+			return nil, nil
+		})
+		receipts := []*pipeline.Receipt{}
+		for i := 0; i < 5; i++ {
+			receipts = append(receipts, p.Submit(i))
+		}
+
+		//	Items submitted while draining are turned away
+		drained := make(chan *pipeline.DrainReport)
+		go func() {
+			drained <- p.Drain(ctx)
+		}()
+		time.Sleep(30 * time.Millisecond)
+		if err := p.Submit(5).Wait(); !errors.Is(err, pool.ErrPoolDraining) {
+			t.Errorf("Expected an item submitted while draining to be turned away, got: %v", err)
+		}
+
+		report := <-drained
+		if !report.Complete() || report.Abandoned() != 0 {
+			t.Errorf("Expected a complete drain, got %+v", report)
+		}
+		for i, receipt := range receipts {
+			select {
+			case <-receipt.Done():
+				if err := receipt.Err(); err != nil {
+					t.Errorf("Unexpected error for item %d: %v", i, err)
+				}
+			default:
+				t.Errorf("Expected item %d to be complete once drained", i)
+			}
+		}
+
+		//	The pipeline accepts items again once drained
+		if err := p.Submit(6).Wait(); err != nil {
+			t.Errorf("Unexpected error after the drain: %v", err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		p := setup(t, func(ctx context.Context) (interface{}, error) {
+			<-release
+			return nil, nil
+		})
+		receipts := []*pipeline.Receipt{}
+		for i := 0; i < 4; i++ {
+			receipts = append(receipts, p.Submit(i))
+		}
+
+		//	The running job holds its item in flight, while the queued ones are abandoned
+		drainCtx, drainCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer drainCancel()
+		report := p.Drain(drainCtx)
+		if report.Complete() || report.Abandoned() == 0 {
+			t.Errorf("Expected the drain to time out and abandon queued jobs, got %d abandoned and %d in flight", report.Abandoned(), report.InFlight)
+		}
+
+		close(release)
+		abandoned := 0
+		for i, receipt := range receipts {
+			err := receipt.Wait()
+			switch {
+			case errors.Is(err, pool.ErrJobAbandoned):
+				abandoned++
+			case err != nil:
+				t.Errorf("Unexpected error for item %d: %v", i, err)
+			}
+		}
+		if abandoned != report.Abandoned() || abandoned == len(receipts) {
+			t.Errorf("Expected a receipt to complete with ErrJobAbandoned per abandoned job, and the running one to succeed, got %d of %d", abandoned, report.Abandoned())
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package pipeline
+
+import (
+	"github.com/coherentopensource/go-service-framework/pool"
+)
+
+// GroupTransformer transforms an input into the members of a group, keyed by job name
+type GroupTransformer func(in interface{}) map[string]pool.Runner
+
+// GroupOf adapts a map of feed transformers (as used by pool.SetGroupInputFeed) into a GroupTransformer
+func GroupOf(transformers map[string]pool.FeedTransformer) GroupTransformer {
+	return func(in interface{}) map[string]pool.Runner {
+		members := make(map[string]pool.Runner, len(transformers))
+		for key, transformer := range transformers {
+			members[key] = transformer(in)
+		}
+		return members
+	}
+}
+
+// stage is a node of the pipeline DAG, backed by a dedicated worker pool
+type stage struct {
+	name         string
+	pool         *pool.WorkerPool
+	transformers []pool.FeedTransformer
+	group        GroupTransformer
+	upstream     []*stage
+	downstream   []*stage
+	emitter      pool.Emitter
+}
+
+// emissionsPerItem is the number of results a stage emits per item when all of its jobs succeed
+func (s *stage) emissionsPerItem() int {
+	if s.group != nil {
+		return 1
+	}
+	return len(s.transformers)
+}
@@ -0,0 +1,90 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/coherentopensource/go-service-framework/pool"
+)
+
+// Receipt tracks the completion of a single item submitted to the pipeline, across every stage it flows through
+type Receipt struct {
+	doneCh chan struct{}
+	errMu  *sync.Mutex
+	errs   []error
+}
+
+// Done returns a channel that is closed once every job spawned for the item has finished
+func (r *Receipt) Done() <-chan struct{} {
+	return r.doneCh
+}
+
+// Wait blocks until the item has completed, then returns its error, if any
+func (r *Receipt) Wait() error {
+	<-r.doneCh
+	return r.Err()
+}
+
+// Err joins the errors of every job of the item that failed so far
+func (r *Receipt) Err() error {
+	r.errMu.Lock()
+	defer r.errMu.Unlock()
+	return errors.Join(r.errs...)
+}
+
+func (r *Receipt) fail(stageName string, err error) {
+	r.errMu.Lock()
+	defer r.errMu.Unlock()
+	r.errs = append(r.errs, fmt.Errorf("stage [%s]: %w", stageName, err))
+}
+
+// item is the internal state of a submitted input, keyed by the WaitGroup that every one of its jobs signals
+type item struct {
+	wg      *sync.WaitGroup
+	receipt *Receipt
+	opts    []pool.PushOpt
+	joinMu  *sync.Mutex
+	joins   map[string]pool.ResultSet
+}
+
+func newItem(opts []pool.PushOpt) *item {
+	return &item{
+		wg: &sync.WaitGroup{},
+		receipt: &Receipt{
+			doneCh: make(chan struct{}),
+			errMu:  &sync.Mutex{},
+		},
+		opts:   opts,
+		joinMu: &sync.Mutex{},
+		joins:  map[string]pool.ResultSet{},
+	}
+}
+
+// pushOpts adds the emitter of a stage to the push options of the item; the jobs of one-off stages are keyed by stage
+// name in the metrics of their pool, while group members keep their own keys
+func (it *item) pushOpts(s *stage) []pool.PushOpt {
+	opts := make([]pool.PushOpt, 0, len(it.opts)+2)
+	opts = append(opts, pool.WithJobKey(s.name))
+	opts = append(opts, it.opts...)
+	return append(opts, pool.WithEmitter(s.emitter))
+}
+
+// join records the result of an upstream stage for a fan-in stage, returning the joined input once every upstream
+// stage has produced its result for this item
+func (it *item) join(down *stage, up *stage, payload interface{}) (pool.ResultSet, bool) {
+	it.joinMu.Lock()
+	defer it.joinMu.Unlock()
+
+	joined, ok := it.joins[down.name]
+	if !ok {
+		joined = pool.ResultSet{}
+		it.joins[down.name] = joined
+	}
+	joined[up.name] = payload
+	if len(joined) < len(down.upstream) {
+		return nil, false
+	}
+	delete(it.joins, down.name)
+	return joined, true
+}
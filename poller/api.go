@@ -1,22 +1,56 @@
 package poller
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/coherentopensource/go-service-framework/pipeline"
+)
+
+var (
+	// ErrNoProgressStore is returned by Audit() and Repair() for pollers without a progress store
+	ErrNoProgressStore = errors.New("poller has no progress store")
+)
+
+// RepairReport summarizes a Repair() run
+type RepairReport struct {
+	Missing  int
+	Repaired int
+	Failed   []uint64
+}
 
 func (p *Poller) Insights() map[string]map[string]int {
-	return map[string]map[string]int{
+	insights := map[string]map[string]int{
 		"fetch-pool":      p.fetchPool.Insights(),
 		"accumulate-pool": p.accumulatePool.Insights(),
 		"write-pool":      p.writePool.Insights(),
+		"pipeline":        p.pipeline.Insights(),
+	}
+	if progress, ok := p.Progress(); ok {
+		insights["backfill"] = map[string]int{
+			"from":       int(progress.Job.From),
+			"to":         int(progress.Job.To),
+			"cursor":     int(progress.Cursor),
+			"percent":    int(progress.Percent),
+			"etaSeconds": int(progress.ETA.Seconds()),
+		}
 	}
+	return insights
 }
 
+// Pause drains the pipeline, then holds the poller until it is resumed; if the drain times out, the queued jobs are
+// abandoned and the batch in progress is left for re-polling on resume, so no partially written block is skipped
 func (p *Poller) Pause() {
 	p.modeMu.Lock()
 	defer p.modeMu.Unlock()
 
-	p.writePool.FlushAndRestart()
-	p.accumulatePool.FlushAndRestart()
-	p.fetchPool.FlushAndRestart()
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.DrainTimeout)
+	defer cancel()
+
+	if report := p.pipeline.Drain(ctx); !report.Complete() {
+		p.logger.Warnf("Pause timed out; %d queued jobs abandoned, the current batch will be re-polled on resume", report.Abandoned())
+	}
 
 	p.mode = ModePaused
 }
@@ -31,3 +65,57 @@ func (p *Poller) Resume() {
 func (p *Poller) SetCursor(ctx context.Context, newVal uint64) error {
 	return p.cache.SetCurrentBlockNumber(ctx, p.cacheKey(), newVal)
 }
+
+// Audit scans the blocks in [from, to) for those that never made it through every writer, e.g. as a job failed
+// while the cursor still advanced
+func (p *Poller) Audit(ctx context.Context, from, to uint64) ([]uint64, error) {
+	if p.progress == nil {
+		return nil, ErrNoProgressStore
+	}
+	missing, err := p.progress.Missing(ctx, p.progressKey, from, to)
+	if err != nil {
+		return nil, err
+	}
+	p.metrics.Gauge(fmt.Sprintf("%s-poller-missing-blocks", p.cfg.Blockchain), float64(len(missing)), []string{}, 1.0)
+	return missing, nil
+}
+
+// Repair re-runs the blocks found missing by Audit() through the pipeline, batchSize blocks at a time, leaving the
+// cursor untouched; it may run alongside the main loop, with which it shares the pools
+func (p *Poller) Repair(ctx context.Context, from, to uint64) (RepairReport, error) {
+	missing, err := p.Audit(ctx, from, to)
+	if err != nil {
+		return RepairReport{}, err
+	}
+
+	report := RepairReport{Missing: len(missing)}
+	batchSize := p.cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	for start := 0; start < len(missing); start += batchSize {
+		end := start + batchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+		receipts := make([]*pipeline.Receipt, 0, end-start)
+		for _, block := range missing[start:end] {
+			receipts = append(receipts, p.submitBlock(ctx, block))
+		}
+		for i, receipt := range receipts {
+			if err := receipt.Wait(); err != nil {
+				p.logger.Errorf("Failed to repair block %d: %v", missing[start+i], err)
+				report.Failed = append(report.Failed, missing[start+i])
+				continue
+			}
+			p.markComplete(missing[start+i])
+			report.Repaired++
+		}
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+	}
+
+	p.logger.Infof("Repaired %d of %d missing blocks in [%d, %d)", report.Repaired, report.Missing, from, to)
+	return report, nil
+}
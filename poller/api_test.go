@@ -0,0 +1,81 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/pipeline"
+)
+
+func TestAuditAndRepair(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("no progress store", func(t *testing.T) {
+		ev := newEvents()
+		p := newTestPoller(t, testConfig(), newFakeDriver(ev, 32), newFakeCache(ev))
+		if _, err := p.Repair(ctx, 0, 10); !errors.Is(err, ErrNoProgressStore) {
+			t.Errorf("Expected ErrNoProgressStore, got: %v", err)
+		}
+	})
+
+	t.Run("batch records completion", func(t *testing.T) {
+		//	Completion is recorded by the time the batch reports, so that the cursor never passes unrecorded blocks
+		ev := newEvents()
+		driver := newFakeDriver(ev, 32)
+		driver.failures[12] = 1
+		store := newFakeProgressStore()
+		p := newTestPoller(t, testConfig(), driver, newFakeCache(ev), WithProgressStore(store))
+
+		receipts := []*pipeline.Receipt{}
+		for block := uint64(10); block < 14; block++ {
+			receipts = append(receipts, p.submitBlock(ctx, block))
+		}
+		if !p.awaitBatch(10, receipts) {
+			t.Fatal("Expected the batch to complete")
+		}
+		missing, err := p.Audit(ctx, 10, 14)
+		if err != nil || fmt.Sprint(missing) != "[12]" {
+			t.Errorf("Expected block 12 to be missing, got %v, %v", missing, err)
+		}
+	})
+
+	t.Run("repair", func(t *testing.T) {
+		ev := newEvents()
+		driver := newFakeDriver(ev, 32)
+		store := newFakeProgressStore()
+		p := newTestPoller(t, testConfig(), driver, newFakeCache(ev), WithProgressStore(store))
+		for block := uint64(10); block < 20; block++ {
+			if block != 12 && block != 15 && block != 17 {
+				store.MarkComplete(ctx, p.progressKey, block)
+			}
+		}
+		//	block 17 fails once more when repaired, and is left for the next repair
+		driver.failures[17] = 1
+
+		report, err := p.Repair(ctx, 10, 20)
+		if err != nil {
+			t.Fatalf("Error repairing: %v", err)
+		}
+		if report.Missing != 3 || report.Repaired != 2 || fmt.Sprint(report.Failed) != "[17]" {
+			t.Errorf("Expected 3 missing, 2 repaired and block 17 failed, got %+v", report)
+		}
+		for _, block := range []uint64{12, 15} {
+			if writes := driver.writes(block); writes != 1 {
+				t.Errorf("Expected block %d to be written once, got %d", block, writes)
+			}
+		}
+		if writes := driver.writes(11); writes != 0 {
+			t.Errorf("Expected complete blocks not to be re-polled, got %d writes of block 11", writes)
+		}
+		if missing, _ := p.Audit(ctx, 10, 20); fmt.Sprint(missing) != "[17]" {
+			t.Errorf("Expected only block 17 to be missing after the repair, got %v", missing)
+		}
+		if report, _ := p.Repair(ctx, 10, 20); report.Repaired != 1 || len(report.Failed) != 0 {
+			t.Errorf("Expected block 17 to be repaired on the next run, got %+v", report)
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/cache"
+)
+
+// BackfillJob bounds a poller to the blocks in [From, To), for historical loads; the poller stops once its cursor
+// reaches To
+type BackfillJob struct {
+	From uint64
+	To   uint64
+}
+
+func (j BackfillJob) String() string {
+	return fmt.Sprintf("%d-%d", j.From, j.To)
+}
+
+// Split divides the job into up to n contiguous shards of roughly equal size, to be run by concurrent pollers
+func (j BackfillJob) Split(n int) []BackfillJob {
+	if j.To <= j.From {
+		return nil
+	}
+	total := j.To - j.From
+	if n <= 0 {
+		n = 1
+	}
+	if uint64(n) > total {
+		n = int(total)
+	}
+
+	shards := make([]BackfillJob, 0, n)
+	from := j.From
+	for i := 0; i < n; i++ {
+		size := total / uint64(n)
+		if uint64(i) < total%uint64(n) {
+			size++
+		}
+		shards = append(shards, BackfillJob{From: from, To: from + size})
+		from += size
+	}
+	return shards
+}
+
+// BackfillProgress reports how far a backfill job has got; the ETA is extrapolated from the pace since the poller
+// started, and is zero until the first batch is written
+type BackfillProgress struct {
+	Job     BackfillJob
+	Cursor  uint64
+	Percent float64
+	ETA     time.Duration
+	Done    bool
+}
+
+// backfill tracks the progress of a poller's backfill job
+type backfill struct {
+	mu          *sync.Mutex
+	job         BackfillJob
+	cursor      uint64
+	startCursor uint64
+	started     time.Time
+	limit       uint64
+	initialized bool
+	doneCh      chan struct{}
+}
+
+// WithBackfillJob bounds the poller to a range of blocks; its cursor is persisted under a key derived from the range,
+// so that several jobs can run side by side
+func WithBackfillJob(job BackfillJob) opt {
+	return func(p *Poller) {
+		p.backfill = &backfill{mu: &sync.Mutex{}, job: job, doneCh: make(chan struct{})}
+	}
+}
+
+// NewBackfill constructs a poller per shard of a backfill job, sharing the same driver, cache and pools; start them
+// all, then wait on Done() for each
+func NewBackfill(cfg *Config, driver Driver, job BackfillJob, shards int, opts ...opt) []*Poller {
+	pollers := []*Poller{}
+	for _, shard := range job.Split(shards) {
+		shardOpts := append(append([]opt{}, opts...), WithBackfillJob(shard))
+		pollers = append(pollers, New(cfg, driver, shardOpts...))
+	}
+	return pollers
+}
+
+// Done is closed once the poller has completed its backfill job; it is never closed for open-ended pollers
+func (p *Poller) Done() <-chan struct{} {
+	if p.backfill == nil {
+		return nil
+	}
+	return p.backfill.doneCh
+}
+
+// Progress reports how far the poller's backfill job has got; returns false for open-ended pollers
+func (p *Poller) Progress() (BackfillProgress, bool) {
+	if p.backfill == nil {
+		return BackfillProgress{}, false
+	}
+	b := p.backfill
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	progress := BackfillProgress{Job: b.job, Cursor: b.cursor, Percent: 100}
+	if total := b.job.To - b.job.From; b.job.To > b.job.From && b.cursor < b.job.To {
+		progress.Percent = float64(b.cursor-b.job.From) / float64(total) * 100
+	}
+	if done := b.cursor - b.startCursor; b.initialized && done > 0 && b.cursor < b.job.To {
+		perBlock := time.Since(b.started) / time.Duration(done)
+		progress.ETA = perBlock * time.Duration(b.job.To-b.cursor)
+	}
+	select {
+	case <-b.doneCh:
+		progress.Done = true
+	default:
+	}
+	return progress, true
+}
+
+// initBackfill starts the job at its first block, unless a cursor was persisted by an earlier run
+func (p *Poller) initBackfill(ctx context.Context, cursor uint64, err error) (uint64, error) {
+	b := p.backfill
+	var missing *cache.NotInRedisCacheError
+	if err != nil && !errors.As(err, &missing) {
+		return 0, err
+	}
+	if err != nil || cursor < b.job.From {
+		cursor = b.job.From
+		if err := p.setCurrentChaintip(ctx, cursor); err != nil {
+			return 0, err
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cursor, b.startCursor, b.started, b.initialized = cursor, cursor, time.Now(), true
+	p.logger.Infof("Backfill job [%s] starting at block %d", b.job, cursor)
+	return cursor, nil
+}
+
+// setBackfillMode pulls batches up to the end of the job, or up to the reorg range if the job reaches into it
+func (p *Poller) setBackfillMode(cursor, maxBlock uint64) {
+	b := p.backfill
+	limit := b.job.To
+	if maxBlock < limit {
+		limit = maxBlock
+	}
+	if cursor >= limit {
+		p.setSleepMode()
+		p.logger.Warnf("Backfill job [%s] is within reorg range; poller going to sleep", b.job)
+		return
+	}
+
+	b.mu.Lock()
+	b.limit = limit
+	b.mu.Unlock()
+	p.mode = ModeBackfill
+	p.windowLimit = limit
+}
+
+// batchSize is the number of blocks to pull from the cursor, which the end of a backfill job may cut short
+func (p *Poller) batchSize(cursor uint64) int {
+	if p.backfill == nil {
+		return p.cfg.BatchSize
+	}
+	p.backfill.mu.Lock()
+	defer p.backfill.mu.Unlock()
+	if remaining := p.backfill.limit - cursor; p.backfill.limit > cursor && remaining < uint64(p.cfg.BatchSize) {
+		return int(remaining)
+	}
+	return p.cfg.BatchSize
+}
+
+// reportProgress records the cursor of a backfill job once a batch is written, emitting its progress and ETA
+func (p *Poller) reportProgress(cursor uint64) {
+	if p.backfill == nil {
+		return
+	}
+	p.backfill.mu.Lock()
+	p.backfill.cursor = cursor
+	p.backfill.mu.Unlock()
+
+	progress, _ := p.Progress()
+	tags := []string{fmt.Sprintf("job:%s", progress.Job)}
+	p.logger.Infof("Backfill job [%s] is %.1f%% done; ETA %v", progress.Job, progress.Percent, progress.ETA.Round(time.Second))
+	p.metrics.Gauge(fmt.Sprintf("%s-backfill-progress", p.cfg.Blockchain), progress.Percent, tags, 1.0)
+	p.metrics.Gauge(fmt.Sprintf("%s-backfill-eta-seconds", p.cfg.Blockchain), progress.ETA.Seconds(), tags, 1.0)
+}
+
+// finishBackfill marks the backfill job as done
+func (p *Poller) finishBackfill() {
+	p.logger.Infof("Backfill job [%s] complete", p.backfill.job)
+	close(p.backfill.doneCh)
+}
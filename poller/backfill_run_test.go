@@ -0,0 +1,104 @@
+package poller
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/cache"
+)
+
+func TestBackfillJob(t *testing.T) {
+	job := BackfillJob{From: 10, To: 22}
+
+	//	run starts a poller on the job, returning once it reports done
+	run := func(t *testing.T, driver *fakeDriver, c *fakeCache) *Poller {
+		cfg := testConfig()
+		cfg.AutoStart = true
+		p := newTestPoller(t, cfg, driver, c, WithBackfillJob(job))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		p.Start(ctx)
+		t.Cleanup(p.Stop)
+		select {
+		case <-p.Done():
+		case <-ctx.Done():
+			t.Fatal("Backfill job never completed")
+		}
+		return p
+	}
+
+	t.Run("stops at the end of the job", func(t *testing.T) {
+		ev := newEvents()
+		driver := newFakeDriver(ev, 100)
+		c := newFakeCache(ev)
+		p := run(t, driver, c)
+
+		if p.Mode() != ModeDone {
+			t.Errorf("Expected the poller to be done, got mode %s", modeToString(p.Mode()))
+		}
+		for block := uint64(0); block <= 30; block++ {
+			expected := 0
+			if block >= job.From && block < job.To {
+				expected = 1
+			}
+			if writes := driver.writes(block); writes != expected {
+				t.Errorf("Expected block %d to be written %d times, got %d", block, expected, writes)
+			}
+		}
+
+		//	The cursor is kept apart from the main one, under a key derived from the job
+		if cursor, ok := c.cursor("test-block-backfill-10-22"); !ok || cursor != job.To {
+			t.Errorf("Expected the job cursor to be persisted at %d, got %d (persisted: %t)", job.To, cursor, ok)
+		}
+		if _, ok := c.cursor("test-block"); ok {
+			t.Error("Expected the main cursor to be left alone")
+		}
+		if progress, ok := p.Progress(); !ok || !progress.Done || progress.Percent != 100 || progress.Cursor != job.To {
+			t.Errorf("Expected the job to be reported done, got %+v", progress)
+		}
+	})
+
+	t.Run("resumes from the persisted cursor", func(t *testing.T) {
+		ev := newEvents()
+		driver := newFakeDriver(ev, 100)
+		c := newFakeCache(ev)
+		c.cursors["test-block-backfill-10-22"] = 16
+		run(t, driver, c)
+
+		for block := job.From; block < job.To; block++ {
+			expected := 0
+			if block >= 16 {
+				expected = 1
+			}
+			if writes := driver.writes(block); writes != expected {
+				t.Errorf("Expected block %d to be written %d times, got %d", block, expected, writes)
+			}
+		}
+	})
+
+	t.Run("progress", func(t *testing.T) {
+		ev := newEvents()
+		p := newTestPoller(t, testConfig(), newFakeDriver(ev, 100), newFakeCache(ev), WithBackfillJob(job))
+		if progress, ok := p.Progress(); !ok || progress.ETA != 0 {
+			t.Errorf("Expected no ETA before the job starts, got %+v", progress)
+		}
+
+		if _, err := p.initBackfill(context.Background(), 0, &cache.NotInRedisCacheError{}); err != nil {
+			t.Fatalf("Error starting backfill job: %v", err)
+		}
+		//	5 blocks in 10s leaves 7 blocks to go, at 2s a block
+		p.backfill.started = time.Now().Add(-10 * time.Second)
+		p.reportProgress(15)
+
+		progress, _ := p.Progress()
+		if progress.Cursor != 15 || math.Abs(progress.Percent-500.0/12) > 0.01 || progress.Done {
+			t.Errorf("Expected the job to be 5/12 done, got %+v", progress)
+		}
+		if progress.ETA < 14*time.Second || progress.ETA > 15*time.Second {
+			t.Errorf("Expected an ETA of about 14s, got %v", progress.ETA)
+		}
+	})
+}
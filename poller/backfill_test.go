@@ -0,0 +1,29 @@
+package poller_test
+
+import (
+	"testing"
+
+	"github.com/coherentopensource/go-service-framework/poller"
+)
+
+func TestBackfillJobSplit(t *testing.T) {
+	shards := poller.BackfillJob{From: 100, To: 110}.Split(3)
+	expected := []poller.BackfillJob{{From: 100, To: 104}, {From: 104, To: 107}, {From: 107, To: 110}}
+	if len(shards) != len(expected) {
+		t.Fatalf("Expected shards %v, got %v", expected, shards)
+	}
+	for i := range expected {
+		if shards[i] != expected[i] {
+			t.Errorf("Expected shards %v, got %v", expected, shards)
+			break
+		}
+	}
+
+	//	A range smaller than the number of shards gets a shard per block
+	if shards := (poller.BackfillJob{From: 5, To: 7}).Split(4); len(shards) != 2 || shards[1] != (poller.BackfillJob{From: 6, To: 7}) {
+		t.Errorf("Expected a shard per block, got %v", shards)
+	}
+	if shards := (poller.BackfillJob{From: 7, To: 7}).Split(4); len(shards) != 0 {
+		t.Errorf("Expected no shards for an empty range, got %v", shards)
+	}
+}
@@ -9,11 +9,14 @@ import (
 type Config struct {
 	Blockchain      constants.Blockchain `env:"BLOCKCHAIN,required"`
 	BatchSize       int                  `env:"BATCH_SIZE" envDefault:"100"`
+	WindowSize      int                  `env:"WINDOW_SIZE" envDefault:"0"`
 	ReorgDepth      int                  `env:"REORG_DEPTH" envDefault:"8"`
+	HashBufferSize  int                  `env:"HASH_BUFFER_SIZE" envDefault:"128"`
 	HttpRetries     int                  `env:"HTTP_RETRIES" envDefault:"10"`
 	SleepTime       time.Duration        `env:"POLLER_SLEEP_TIME" envDefault:"12s"`
 	Tick            time.Duration        `env:"POLLER_TICK_DURATION" envDefault:"1s"`
 	AutoStart       bool                 `env:"POLLER_AUTO_START" envDefault:"false"`
 	CursorKey       string               `env:"CURSOR_KEY" envDefault:""`
 	IsTraceBackfill bool                 `env:"IS_TRACE_BACKFILL" envDefault:"false"`
+	DrainTimeout    time.Duration        `env:"POLLER_DRAIN_TIMEOUT" envDefault:"30s"`
 }
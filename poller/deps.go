@@ -15,7 +15,36 @@ type Driver interface {
 	Writers() []pool.FeedTransformer
 }
 
+// BlockHasher is implemented by drivers able to report the hash and parent hash of a block, enabling the poller to
+// detect reorgs and find their fork point
+type BlockHasher interface {
+	GetBlockHash(ctx context.Context, index uint64) (hash string, parentHash string, err error)
+}
+
+// Rollbacker is implemented by drivers able to undo the data written for every block from fromBlock onwards; the
+// poller calls it on a reorg, before rewinding its cursor to the fork point
+type Rollbacker interface {
+	Rollback(ctx context.Context, fromBlock uint64) error
+}
+
 type Cache interface {
 	GetCurrentBlockNumber(ctx context.Context, blockChainInfoKey string) (uint64, error)
 	SetCurrentBlockNumber(ctx context.Context, blockChainInfoKey string, blockNumber uint64) error
 }
+
+// HashStore is implemented by caches able to hold the ring buffer of recent block hashes used for reorg detection,
+// such as *cache.Cache
+type HashStore interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value interface{}) error
+}
+
+// ProgressStore records which blocks have made it through every writer, so that gaps can be audited and repaired;
+// see the progress package for Redis and Postgres implementations
+type ProgressStore interface {
+	MarkComplete(ctx context.Context, key string, block uint64) error
+	//	Missing lists the blocks in [from, to) never marked complete
+	Missing(ctx context.Context, key string, from, to uint64) ([]uint64, error)
+	//	Clear marks the blocks in [from, to) as incomplete again, e.g. as they were rolled back by a reorg
+	Clear(ctx context.Context, key string, from, to uint64) error
+}
@@ -0,0 +1,303 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/cache"
+	"github.com/coherentopensource/go-service-framework/metrics"
+	"github.com/coherentopensource/go-service-framework/pool"
+	"go.uber.org/zap"
+)
+
+// events records the calls made to the fakes, in order, across all of them
+type events struct {
+	mu  *sync.Mutex
+	log []string
+}
+
+func newEvents() *events {
+	return &events{mu: &sync.Mutex{}}
+}
+
+func (e *events) add(format string, args ...interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.log = append(e.log, fmt.Sprintf(format, args...))
+}
+
+func (e *events) all() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string{}, e.log...)
+}
+
+// fakeDriver serves a chain of blocks whose hashes can be rewritten to simulate reorgs; fetching a block can be
+// delayed or made to fail
+type fakeDriver struct {
+	mu       *sync.Mutex
+	events   *events
+	tip      uint64
+	hashes   map[uint64]string
+	delays   map[uint64]time.Duration
+	failures map[uint64]int
+	written  map[uint64]int
+}
+
+func newFakeDriver(ev *events, tip uint64) *fakeDriver {
+	d := &fakeDriver{
+		mu:       &sync.Mutex{},
+		events:   ev,
+		tip:      tip,
+		hashes:   map[uint64]string{},
+		delays:   map[uint64]time.Duration{},
+		failures: map[uint64]int{},
+		written:  map[uint64]int{},
+	}
+	for block := uint64(0); block <= tip; block++ {
+		d.hashes[block] = fmt.Sprintf("0x%d", block)
+	}
+	return d
+}
+
+// fork rewrites the hashes of every block from the given one onwards
+func (d *fakeDriver) fork(from uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for block := from; block <= d.tip; block++ {
+		d.hashes[block] = fmt.Sprintf("0x%d'", block)
+	}
+}
+
+func (d *fakeDriver) writes(block uint64) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.written[block]
+}
+
+func (d *fakeDriver) Blockchain() string {
+	return "test"
+}
+
+func (d *fakeDriver) GetChainTipNumber(ctx context.Context) (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.tip, nil
+}
+
+func (d *fakeDriver) IsValidBlock(ctx context.Context, index uint64) error {
+	return nil
+}
+
+func (d *fakeDriver) FetchSequence(index uint64) map[string]pool.Runner {
+	return map[string]pool.Runner{"block": func(ctx context.Context) (interface{}, error) {
+		d.mu.Lock()
+		delay := d.delays[index]
+		failing := d.failures[index] > 0
+		if failing {
+			d.failures[index]--
+		}
+		d.mu.Unlock()
+
+		time.Sleep(delay)
+		if failing {
+			return nil, fmt.Errorf("fetching block %d: node unavailable", index)
+		}
+		return index, nil
+	}}
+}
+
+func (d *fakeDriver) Accumulate(res interface{}) pool.Runner {
+	return func(ctx context.Context) (interface{}, error) {
+		return res.(pool.ResultSet)["block"], nil
+	}
+}
+
+func (d *fakeDriver) Writers() []pool.FeedTransformer {
+	return []pool.FeedTransformer{func(res interface{}) pool.Runner {
+		return func(ctx context.Context) (interface{}, error) {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.written[res.(uint64)]++
+			d.events.add("write:%d", res.(uint64))
+			return nil, nil
+		}
+	}}
+}
+
+func (d *fakeDriver) GetBlockHash(ctx context.Context, index uint64) (string, string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events.add("hash:%d", index)
+	if index == 0 {
+		return d.hashes[0], "", nil
+	}
+	return d.hashes[index], d.hashes[index-1], nil
+}
+
+func (d *fakeDriver) Rollback(ctx context.Context, fromBlock uint64) error {
+	d.events.add("rollback:%d", fromBlock)
+	return nil
+}
+
+// fakeCache holds cursors and block hashes in memory; it can be made to fail to simulate Redis being down
+type fakeCache struct {
+	mu      *sync.Mutex
+	events  *events
+	cursors map[string]uint64
+	values  map[string]string
+	err     error
+}
+
+func newFakeCache(ev *events) *fakeCache {
+	return &fakeCache{mu: &sync.Mutex{}, events: ev, cursors: map[string]uint64{}, values: map[string]string{}}
+}
+
+func (c *fakeCache) cursor(key string) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cursor, ok := c.cursors[key]
+	return cursor, ok
+}
+
+func (c *fakeCache) GetCurrentBlockNumber(ctx context.Context, key string) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return 0, c.err
+	}
+	cursor, ok := c.cursors[key]
+	if !ok {
+		return 0, &cache.NotInRedisCacheError{}
+	}
+	return cursor, nil
+}
+
+func (c *fakeCache) SetCurrentBlockNumber(ctx context.Context, key string, block uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.events.add("cursor:%d", block)
+	c.cursors[key] = block
+	return nil
+}
+
+func (c *fakeCache) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return "", c.err
+	}
+	value, ok := c.values[key]
+	if !ok {
+		return "", &cache.NotInRedisCacheError{}
+	}
+	return value, nil
+}
+
+func (c *fakeCache) Set(ctx context.Context, key string, value interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.values[key] = value.(string)
+	return nil
+}
+
+// fakeProgressStore records completed blocks in memory
+type fakeProgressStore struct {
+	mu        *sync.Mutex
+	completed map[string]map[uint64]bool
+}
+
+func newFakeProgressStore() *fakeProgressStore {
+	return &fakeProgressStore{mu: &sync.Mutex{}, completed: map[string]map[uint64]bool{}}
+}
+
+func (s *fakeProgressStore) MarkComplete(ctx context.Context, key string, block uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.completed[key] == nil {
+		s.completed[key] = map[uint64]bool{}
+	}
+	s.completed[key][block] = true
+	return nil
+}
+
+func (s *fakeProgressStore) Clear(ctx context.Context, key string, from, to uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for block := from; block < to; block++ {
+		delete(s.completed[key], block)
+	}
+	return nil
+}
+
+func (s *fakeProgressStore) Missing(ctx context.Context, key string, from, to uint64) ([]uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	missing := []uint64{}
+	for block := from; block < to; block++ {
+		if !s.completed[key][block] {
+			missing = append(missing, block)
+		}
+	}
+	return missing, nil
+}
+
+var errCacheDown = errors.New("cache unavailable")
+
+// testConfig returns a config polling fast, without retries, so that tests neither sleep nor hang
+func testConfig() *Config {
+	return &Config{
+		Blockchain:     "test",
+		BatchSize:      4,
+		ReorgDepth:     2,
+		HashBufferSize: 8,
+		HttpRetries:    1,
+		SleepTime:      10 * time.Millisecond,
+		Tick:           time.Millisecond,
+		DrainTimeout:   time.Second,
+	}
+}
+
+// newTestPoller builds a poller on running pools; the pools are stopped at the end of the test
+func newTestPoller(t *testing.T, cfg *Config, driver Driver, c Cache, opts ...opt) *Poller {
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+	noop, _ := metrics.NewNoopMetrics()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pools := []*pool.WorkerPool{}
+	for _, name := range []string{"fetch", "accumulate", "write"} {
+		wp := pool.NewWorkerPool(name, pool.WithLogger(logger))
+		wp.Start(ctx)
+		pools = append(pools, wp)
+	}
+	t.Cleanup(func() {
+		cancel()
+		for _, wp := range pools {
+			wp.Stop()
+		}
+	})
+
+	opts = append([]opt{
+		WithFetchPool(pools[0]),
+		WithAccumulatePool(pools[1]),
+		WithWritePool(pools[2]),
+		WithCache(c),
+		WithLogger(logger),
+		WithMetrics(noop),
+	}, opts...)
+	return New(cfg, driver, opts...)
+}
@@ -7,9 +7,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/coherentopensource/go-service-framework/circuitbreaker"
 	"github.com/coherentopensource/go-service-framework/constants"
+	"github.com/coherentopensource/go-service-framework/pipeline"
 	"github.com/coherentopensource/go-service-framework/pool"
 	"github.com/coherentopensource/go-service-framework/util"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Modes determine what the poller does on each iteration of its main routine's loop; these are determined by
@@ -25,6 +28,8 @@ const (
 	ModeBackfill
 	//	ModeChaintip means the poller is < batchSize blocks from chaintip and will pull one block at a time
 	ModeChaintip
+	//	ModeDone means the poller has reached the end of its backfill job and has stopped
+	ModeDone
 )
 
 // Poller is a chain-agnostic module for ETLing blockchain data, utilizing worker pools to optimize
@@ -42,6 +47,13 @@ type Poller struct {
 	writePool      *pool.WorkerPool
 	cancelFunc     context.CancelFunc
 	runCtx         context.Context
+	pipeline       *pipeline.Pipeline
+	tracer         trace.Tracer
+	breaker        *circuitbreaker.Breaker
+	backfill       *backfill
+	windowLimit    uint64
+	progress       ProgressStore
+	progressKey    string
 	cursorKey      string
 }
 
@@ -61,17 +73,28 @@ func New(cfg *Config, driver Driver, opts ...opt) *Poller {
 	for _, opt := range opts {
 		opt(&p)
 	}
+	//	A config built by hand, rather than parsed from the environment, would otherwise time out every Pause() at once
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = pipeline.DefaultDrainTimeout
+	}
 
-	p.accumulatePool.SetInputFeed(p.fetchPool.Results(), p.driver.Accumulate)
-	p.writePool.SetInputFeed(p.accumulatePool.Results(), p.driver.Writers()...)
+	if err := p.buildPipeline(); err != nil {
+		p.logger.Fatalf("failed to build poller pipeline: %v", err)
+	}
 
 	p.cursorKey = strings.TrimSpace(cfg.CursorKey)
 	if p.cursorKey == "" {
-		if p.cfg.IsTraceBackfill {
+		if p.cfg.IsTraceBackfill && p.backfill == nil {
 			p.logger.Fatal("cursor key must be set when trace backfill is enabled")
 		}
 		p.cursorKey = fmt.Sprintf("%s-%s", p.driver.Blockchain(), constants.BlockKey)
 	}
+	//	Block completion is recorded against the main cursor key, so that every backfill job feeds the same audit
+	p.progressKey = p.cursorKey
+	//	Backfill jobs persist their cursor apart from the main one, and from each other
+	if p.backfill != nil {
+		p.cursorKey = fmt.Sprintf("%s-backfill-%s", p.cursorKey, p.backfill.job)
+	}
 
 	return &p
 }
@@ -114,30 +137,56 @@ func (p *Poller) Start(ctx context.Context) error {
 				p.logger.Info("Sleep mode detected; sleeping for this cycle")
 				time.Sleep(1 * time.Second)
 				continue
+			case ModeDone:
+				//	If the backfill job is complete, stop polling
+				p.finishBackfill()
+				return
 			case ModeBackfill:
+				//	If a sliding window is configured, keep it full until the backfill limit, then update the cursor
+				if p.cfg.WindowSize > 0 {
+					p.logger.Infof("Window mode: start polling at block %d with window size %d", cursor, p.cfg.WindowSize)
+					cursor = p.runWindow(ctx, cursor, p.windowLimit)
+					break
+				}
 				//	If in ""backfill" mode, consume a batch of blocks and update the cursor
-				p.logger.Infof("Batch mode: start polling at block %d with batch size %d", cursor, p.cfg.BatchSize)
-				wg := sync.WaitGroup{}
+				batchSize := p.batchSize(cursor)
+				p.logger.Infof("Batch mode: start polling at block %d with batch size %d", cursor, batchSize)
+				receipts := make([]*pipeline.Receipt, 0, batchSize)
 				startIndex := cursor
-				for i := 0; i < p.cfg.BatchSize; i++ {
-					wg.Add(p.driverTaskLoad())
-					p.fetchPool.PushGroup(p.driver.FetchSequence(startIndex+uint64(i)), &wg)
+				for i := 0; i < batchSize; i++ {
+					receipts = append(receipts, p.submitBlock(ctx, startIndex+uint64(i)))
+				}
+				if !p.awaitBatch(startIndex, receipts) {
+					continue
 				}
-				wg.Wait()
-				cursor = startIndex + uint64(p.cfg.BatchSize)
+				cursor = startIndex + uint64(batchSize)
 			case ModeChaintip:
 				//	If in "chaintip" mode, pull the latest block, validate it, then consume it
 				p.logger.Infof("Chaintip mode: pulling block %d", cursor)
-				if p.driver.IsValidBlock(ctx, cursor); err != nil {
+				if err := p.driver.IsValidBlock(ctx, cursor); err != nil {
 					p.logger.Errorf("Invalid block (possible reorg detected) - %v", err)
 					//	Sleep for N seconds if invalid block is detected
 					p.setSleepMode()
 					continue
 				}
-				wg := sync.WaitGroup{}
-				wg.Add(p.driverTaskLoad())
-				p.fetchPool.PushGroup(p.driver.FetchSequence(cursor), &wg)
-				wg.Wait()
+				//	If the block doesn't extend the blocks already polled, roll back to the fork point and start over
+				hash, reorged, err := p.checkReorg(ctx, cursor)
+				if err != nil {
+					p.logger.Errorf("Failed to check for reorg at block %d: %v", cursor, err)
+					//	Sleep rather than spinning while the node or the cache is unavailable
+					p.setSleepMode()
+					continue
+				}
+				if reorged {
+					continue
+				}
+				receipt := p.submitBlock(ctx, cursor, pool.WithPriority(pool.PriorityHigh))
+				if !p.awaitBatch(cursor, []*pipeline.Receipt{receipt}) {
+					continue
+				}
+				if err := p.recordHash(ctx, cursor, hash); err != nil {
+					p.logger.Errorf("Failed to record hash of block %d: %v", cursor, err)
+				}
 				cursor++
 			}
 
@@ -146,6 +195,7 @@ func (p *Poller) Start(ctx context.Context) error {
 				p.logger.Errorf("failed to update block chain tip within redis: %v", err)
 				continue
 			}
+			p.reportProgress(cursor)
 
 			//	Log/stat update
 			p.logger.Infof("finished polling at block %d with batch size %d", cursor, p.cfg.BatchSize)
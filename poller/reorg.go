@@ -0,0 +1,195 @@
+package poller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/coherentopensource/go-service-framework/cache"
+	"github.com/coherentopensource/go-service-framework/retry"
+	"github.com/coherentopensource/go-service-framework/util"
+)
+
+// hashRing is a fixed-size ring buffer of the hashes of recently polled blocks, indexed by block number; it is kept
+// in the cache so that it survives restarts
+type hashRing struct {
+	Entries []hashEntry `json:"entries"`
+}
+
+type hashEntry struct {
+	Number uint64 `json:"number"`
+	Hash   string `json:"hash"`
+}
+
+func (r *hashRing) get(number uint64) (string, bool) {
+	if len(r.Entries) == 0 {
+		return "", false
+	}
+	entry := r.Entries[number%uint64(len(r.Entries))]
+	return entry.Hash, entry.Hash != "" && entry.Number == number
+}
+
+func (r *hashRing) put(number uint64, hash string) {
+	r.Entries[number%uint64(len(r.Entries))] = hashEntry{Number: number, Hash: hash}
+}
+
+// truncate forgets the hashes of every block from the given one onwards
+func (r *hashRing) truncate(from uint64) {
+	for i, entry := range r.Entries {
+		if entry.Number >= from {
+			r.Entries[i] = hashEntry{}
+		}
+	}
+}
+
+func (p *Poller) hashKey() string {
+	return fmt.Sprintf("%s-hashes", p.cacheKey())
+}
+
+// reorgDetection returns the driver and cache capabilities needed for reorg detection, if both are available
+func (p *Poller) reorgDetection() (BlockHasher, HashStore, bool) {
+	hasher, ok := p.driver.(BlockHasher)
+	if !ok {
+		return nil, nil, false
+	}
+	store, ok := p.cache.(HashStore)
+	if !ok || p.cfg.HashBufferSize <= 0 {
+		return nil, nil, false
+	}
+	return hasher, store, true
+}
+
+// loadHashes reads the ring buffer of recent block hashes from the cache; a missing buffer, or one of a different
+// size, is replaced with an empty one
+func (p *Poller) loadHashes(ctx context.Context, store HashStore) (*hashRing, error) {
+	ring := &hashRing{}
+	raw, err := store.Get(ctx, p.hashKey())
+	var missing *cache.NotInRedisCacheError
+	switch {
+	case errors.As(err, &missing):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal([]byte(raw), ring); err != nil {
+			p.logger.Warnf("Discarding unreadable block hashes: %v", err)
+		}
+	}
+	if len(ring.Entries) != p.cfg.HashBufferSize {
+		ring.Entries = make([]hashEntry, p.cfg.HashBufferSize)
+	}
+	return ring, nil
+}
+
+func (p *Poller) saveHashes(ctx context.Context, store HashStore, ring *hashRing) error {
+	raw, err := json.Marshal(ring)
+	if err != nil {
+		return err
+	}
+	return store.Set(ctx, p.hashKey(), string(raw))
+}
+
+// recordHash adds the hash of a block that has been written to the ring buffer
+func (p *Poller) recordHash(ctx context.Context, number uint64, hash string) error {
+	_, store, ok := p.reorgDetection()
+	if !ok || hash == "" {
+		return nil
+	}
+	ring, err := p.loadHashes(ctx, store)
+	if err != nil {
+		return err
+	}
+	ring.put(number, hash)
+	return p.saveHashes(ctx, store, ring)
+}
+
+// checkReorg compares the parent hash of the block at the cursor with the recorded hash of its predecessor; on a
+// mismatch, it finds the fork point, has the driver roll back the data written past it, and rewinds the cursor to
+// it. Returns the hash of the block at the cursor, to be recorded once it is written, and whether a reorg was
+// handled, in which case the block must not be polled
+func (p *Poller) checkReorg(ctx context.Context, cursor uint64) (string, bool, error) {
+	hasher, store, ok := p.reorgDetection()
+	if !ok || cursor == 0 {
+		return "", false, nil
+	}
+
+	hash, parentHash, err := p.blockHash(ctx, hasher, cursor)
+	if err != nil {
+		return "", false, fmt.Errorf("getting hash of block %d: %w", cursor, err)
+	}
+	ring, err := p.loadHashes(ctx, store)
+	if err != nil {
+		return "", false, fmt.Errorf("loading block hashes: %w", err)
+	}
+	if known, ok := ring.get(cursor - 1); !ok || known == parentHash {
+		return hash, false, nil
+	}
+
+	fork, err := p.findForkPoint(ctx, hasher, ring, cursor-1)
+	if err != nil {
+		return "", false, fmt.Errorf("finding fork point: %w", err)
+	}
+	depth := cursor - fork
+	p.logger.Warnf("Reorg detected at block %d; rewinding %d blocks to fork point %d", cursor, depth, fork)
+	p.metrics.Incr(fmt.Sprintf("%s-poller-reorgs", p.cfg.Blockchain), []string{}, 1.0)
+	if distributions, ok := p.metrics.(util.DistributionMetrics); ok {
+		distributions.Histogram(fmt.Sprintf("%s-poller-reorg-depth", p.cfg.Blockchain), float64(depth), []string{}, 1.0)
+	}
+
+	if rollbacker, ok := p.driver.(Rollbacker); ok {
+		if err := rollbacker.Rollback(ctx, fork); err != nil {
+			return "", false, fmt.Errorf("rolling back to block %d: %w", fork, err)
+		}
+	}
+	//	The orphaned blocks no longer count as written, so that they are audited as missing should the re-poll fail
+	if p.progress != nil {
+		if err := p.progress.Clear(ctx, p.progressKey, fork, cursor); err != nil {
+			return "", false, fmt.Errorf("clearing completion of blocks %d to %d: %w", fork, cursor, err)
+		}
+	}
+	ring.truncate(fork)
+	if err := p.saveHashes(ctx, store, ring); err != nil {
+		return "", false, fmt.Errorf("saving block hashes: %w", err)
+	}
+	if err := p.setCurrentChaintip(ctx, fork); err != nil {
+		return "", false, fmt.Errorf("rewinding cursor to block %d: %w", fork, err)
+	}
+	return "", true, nil
+}
+
+// findForkPoint walks back from the given block to the most recent one whose recorded hash still matches the chain,
+// returning the block after it, i.e. the first one to re-poll; if the reorg is deeper than the recorded hashes, the
+// oldest recorded block is re-polled
+func (p *Poller) findForkPoint(ctx context.Context, hasher BlockHasher, ring *hashRing, number uint64) (uint64, error) {
+	for {
+		known, ok := ring.get(number)
+		if !ok {
+			p.logger.Errorf("Reorg is deeper than the %d recorded block hashes; re-polling from block %d", len(ring.Entries), number+1)
+			return number + 1, nil
+		}
+		hash, _, err := p.blockHash(ctx, hasher, number)
+		if err != nil {
+			return 0, err
+		}
+		if hash == known {
+			return number + 1, nil
+		}
+		if number == 0 {
+			return 0, nil
+		}
+		number--
+	}
+}
+
+// blockHash pulls the hash and parent hash of a block from the node
+func (p *Poller) blockHash(ctx context.Context, hasher BlockHasher, number uint64) (string, string, error) {
+	var hash, parentHash string
+	err := retry.Do(ctx, p.retryPolicy(), func() error {
+		return p.guard(func() error {
+			var err error
+			hash, parentHash, err = hasher.GetBlockHash(ctx, number)
+			return err
+		})
+	})
+	return hash, parentHash, err
+}
@@ -0,0 +1,140 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCheckReorg(t *testing.T) {
+	ctx := context.Background()
+
+	//	setup returns a poller whose ring holds the hashes of the given blocks, as the node first reported them
+	setup := func(t *testing.T, from, to uint64) (*Poller, *fakeDriver, *fakeCache, *events) {
+		ev := newEvents()
+		driver := newFakeDriver(ev, 32)
+		c := newFakeCache(ev)
+		p := newTestPoller(t, testConfig(), driver, c)
+		for block := from; block < to; block++ {
+			if err := p.recordHash(ctx, block, driver.hashes[block]); err != nil {
+				t.Fatalf("Error recording hash of block %d: %v", block, err)
+			}
+		}
+		return p, driver, c, ev
+	}
+	expectEvents := func(t *testing.T, ev *events, expected ...string) {
+		var got []string
+		for _, event := range ev.all() {
+			if event[:5] != "hash:" {
+				got = append(got, event)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("Expected rollback and rewind %v, got %v", expected, got)
+		}
+	}
+
+	t.Run("matching parent", func(t *testing.T) {
+		p, _, _, ev := setup(t, 10, 14)
+		hash, reorged, err := p.checkReorg(ctx, 14)
+		if err != nil || reorged || hash != "0x14" {
+			t.Errorf("Expected no reorg and the hash of block 14, got %q, %v, %v", hash, reorged, err)
+		}
+		expectEvents(t, ev)
+	})
+
+	t.Run("single block reorg", func(t *testing.T) {
+		p, driver, c, ev := setup(t, 10, 14)
+		driver.fork(13)
+		_, reorged, err := p.checkReorg(ctx, 14)
+		if err != nil || !reorged {
+			t.Fatalf("Expected a reorg, got %v, %v", reorged, err)
+		}
+		//	The driver rolls back before the cursor is rewound, so a crash in between re-polls rather than skips
+		expectEvents(t, ev, "rollback:13", "cursor:13")
+		if cursor, _ := c.cursor(p.cacheKey()); cursor != 13 {
+			t.Errorf("Expected the cursor to be rewound to 13, got %d", cursor)
+		}
+		ring, _ := p.loadHashes(ctx, c)
+		if _, ok := ring.get(13); ok {
+			t.Error("Expected the hash of the orphaned block to be forgotten")
+		}
+		if _, ok := ring.get(12); !ok {
+			t.Error("Expected the hash of the fork point's parent to be kept")
+		}
+	})
+
+	t.Run("reorg clears completion", func(t *testing.T) {
+		p, driver, _, _ := setup(t, 10, 14)
+		store := newFakeProgressStore()
+		p.progress = store
+		for block := uint64(10); block < 14; block++ {
+			store.MarkComplete(ctx, p.progressKey, block)
+		}
+		driver.fork(12)
+		if _, reorged, err := p.checkReorg(ctx, 14); err != nil || !reorged {
+			t.Fatalf("Expected a reorg, got %v, %v", reorged, err)
+		}
+		if missing, _ := p.Audit(ctx, 10, 14); fmt.Sprint(missing) != "[12 13]" {
+			t.Errorf("Expected the orphaned blocks to be missing, got %v", missing)
+		}
+	})
+
+	t.Run("multi block reorg", func(t *testing.T) {
+		p, driver, _, ev := setup(t, 10, 14)
+		driver.fork(11)
+		if _, reorged, err := p.checkReorg(ctx, 14); err != nil || !reorged {
+			t.Fatalf("Expected a reorg, got %v, %v", reorged, err)
+		}
+		expectEvents(t, ev, "rollback:11", "cursor:11")
+	})
+
+	t.Run("reorg deeper than the buffer", func(t *testing.T) {
+		//	the ring holds 8 hashes, and every one of them is orphaned
+		p, driver, _, ev := setup(t, 10, 18)
+		driver.fork(5)
+		if _, reorged, err := p.checkReorg(ctx, 18); err != nil || !reorged {
+			t.Fatalf("Expected a reorg, got %v, %v", reorged, err)
+		}
+		expectEvents(t, ev, "rollback:10", "cursor:10")
+	})
+
+	t.Run("missing ring", func(t *testing.T) {
+		p, driver, _, ev := setup(t, 0, 0)
+		driver.fork(13)
+		hash, reorged, err := p.checkReorg(ctx, 14)
+		if err != nil || reorged || hash != "0x14'" {
+			t.Errorf("Expected nothing to compare against, got %q, %v, %v", hash, reorged, err)
+		}
+		expectEvents(t, ev)
+	})
+
+	t.Run("resized ring", func(t *testing.T) {
+		p, driver, c, ev := setup(t, 10, 14)
+		p.cfg.HashBufferSize = 16
+		driver.fork(13)
+		if _, reorged, err := p.checkReorg(ctx, 14); err != nil || reorged {
+			t.Errorf("Expected a ring of another size to be discarded, got %v, %v", reorged, err)
+		}
+		expectEvents(t, ev)
+
+		//	the replacement ring works at the new size
+		if err := p.recordHash(ctx, 14, "0x14'"); err != nil {
+			t.Fatalf("Error recording hash: %v", err)
+		}
+		ring, _ := p.loadHashes(ctx, c)
+		if hash, ok := ring.get(14); !ok || hash != "0x14'" || len(ring.Entries) != 16 {
+			t.Errorf("Expected a 16-entry ring holding block 14, got %d entries", len(ring.Entries))
+		}
+	})
+
+	t.Run("cache unavailable", func(t *testing.T) {
+		p, _, c, ev := setup(t, 10, 14)
+		c.err = errCacheDown
+		if _, _, err := p.checkReorg(ctx, 14); !errors.Is(err, errCacheDown) {
+			t.Errorf("Expected the cache error, got: %v", err)
+		}
+		expectEvents(t, ev)
+	})
+}
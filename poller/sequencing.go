@@ -17,6 +17,9 @@ func (p *Poller) setModeAndGetCursor(ctx context.Context) (uint64, error) {
 	defer p.modeMu.Unlock()
 
 	cursor, err := p.getCurrentChaintip(ctx)
+	if p.backfill != nil && !p.backfill.initialized {
+		cursor, err = p.initBackfill(ctx, cursor, err)
+	}
 	if err != nil {
 		return 0, errors.Errorf("Error getting current chaintip: %v", err)
 	}
@@ -25,12 +28,29 @@ func (p *Poller) setModeAndGetCursor(ctx context.Context) (uint64, error) {
 		return cursor, nil
 	}
 
+	//	A backfill job is done once its cursor reaches the end of its range
+	if p.backfill != nil && cursor >= p.backfill.job.To {
+		p.mode = ModeDone
+		return cursor, nil
+	}
+
+	//	An open breaker means the node is failing; sleep rather than hammering it
+	if p.breakerOpen() {
+		p.setSleepMode()
+		p.logger.Warn("Circuit breaker is open; poller going to sleep")
+		return cursor, nil
+	}
+
 	chainTip, err := p.getRemoteChaintip(ctx)
 	if err != nil {
 		return 0, errors.Errorf("Error getting remote chaintip: %v", err)
 	}
 
 	maxBlock := chainTip - uint64(p.cfg.ReorgDepth)
+	if p.backfill != nil {
+		p.setBackfillMode(cursor, maxBlock)
+		return cursor, nil
+	}
 	distanceToMaxBlock := maxBlock - cursor
 
 	switch {
@@ -44,6 +64,7 @@ func (p *Poller) setModeAndGetCursor(ctx context.Context) (uint64, error) {
 	//	Cursor is distant enough from chaintip that we can pull batches
 	default:
 		p.mode = ModeBackfill
+		p.windowLimit = maxBlock
 	}
 
 	return cursor, nil
@@ -61,23 +82,22 @@ func (p *Poller) getCurrentChaintip(ctx context.Context) (uint64, error) {
 
 // setCurrentChaintip overwrites the current cached local chaintip value
 func (p *Poller) setCurrentChaintip(ctx context.Context, newTip uint64) error {
-	return retry.Exec(p.cfg.HttpRetries, func() error {
+	return retry.Do(ctx, p.retryPolicy(), func() error {
 		p.metrics.Gauge(fmt.Sprintf("%s-poller-cursor", p.cfg.Blockchain), float64(newTip), []string{}, 1.0)
 		return p.cache.SetCurrentBlockNumber(ctx, p.cacheKey(), newTip)
-	}, nil)
+	})
 }
 
 // getRemoteChaintip pulls the remote chaintip value
 func (p *Poller) getRemoteChaintip(ctx context.Context) (uint64, error) {
 	var chainTip uint64
-	var err error
-	retry.Exec(p.cfg.HttpRetries, func() error {
-		chainTip, err = p.driver.GetChainTipNumber(ctx)
-		if err != nil {
+	err := retry.Do(ctx, p.retryPolicy(), func() error {
+		return p.guard(func() error {
+			var err error
+			chainTip, err = p.driver.GetChainTipNumber(ctx)
 			return err
-		}
-		return nil
-	}, nil)
+		})
+	})
 	p.metrics.Gauge(fmt.Sprintf("%s-poller-chaintip", p.cfg.Blockchain), float64(chainTip), []string{}, 1.0)
 	return chainTip, err
 }
@@ -1,14 +1,24 @@
 package poller
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/circuitbreaker"
+	"github.com/coherentopensource/go-service-framework/pipeline"
+	"github.com/coherentopensource/go-service-framework/pool"
+	"github.com/coherentopensource/go-service-framework/retry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
 func (p *Poller) cacheKey() string {
 	return p.cursorKey
 }
 
-func (p *Poller) driverTaskLoad() int {
-	//	count of fetchers + count of accumulators (always 1) + count of writers == number of queued jobs per block
-	return len(p.driver.FetchSequence(0)) + 1 + len(p.driver.Writers())
-}
-
 func modeToString(mode int) string {
 	out := "unknown"
 	switch mode {
@@ -22,6 +32,129 @@ func modeToString(mode int) string {
 		out = "backfill"
 	case ModeChaintip:
 		out = "chaintip"
+	case ModeDone:
+		out = "done"
 	}
 	return out
 }
+
+// buildPipeline declares the fetch -> accumulate -> write pipeline that every block flows through
+func (p *Poller) buildPipeline() error {
+	p.pipeline = pipeline.New(fmt.Sprintf("%s-poller", p.driver.Blockchain()), pipeline.WithLogger(p.logger))
+	fetch := func(in interface{}) map[string]pool.Runner {
+		return p.driver.FetchSequence(in.(uint64))
+	}
+	if err := p.pipeline.AddGroupStage("fetch", p.fetchPool, fetch); err != nil {
+		return err
+	}
+	if err := p.pipeline.AddStage("accumulate", p.accumulatePool, p.driver.Accumulate); err != nil {
+		return err
+	}
+	if err := p.pipeline.AddStage("write", p.writePool, p.driver.Writers()...); err != nil {
+		return err
+	}
+	if err := p.pipeline.Connect("fetch", "accumulate"); err != nil {
+		return err
+	}
+	if err := p.pipeline.Connect("accumulate", "write"); err != nil {
+		return err
+	}
+	return p.pipeline.Build()
+}
+
+// submitBlock submits a block to the pipeline, under a span of its own if the poller has a tracer; the span ends once
+// every job of the block has completed
+func (p *Poller) submitBlock(ctx context.Context, block uint64, opts ...pool.PushOpt) *pipeline.Receipt {
+	opts = append(opts, pool.WithDescriptor(fmt.Sprint(block)))
+	var span trace.Span
+	if p.tracer != nil {
+		ctx, span = p.tracer.Start(ctx, "poller.block", trace.WithAttributes(
+			attribute.Int64("block.number", int64(block)),
+			attribute.String("blockchain", string(p.driver.Blockchain())),
+			attribute.String("poller.mode", modeToString(p.mode)),
+		))
+		opts = append(opts, pool.WithTraceContext(ctx))
+	}
+	receipt := p.pipeline.Submit(block, opts...)
+	if span == nil {
+		return receipt
+	}
+
+	go func() {
+		if err := receipt.Wait(); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+	return receipt
+}
+
+// markComplete records a block as having made it through every writer; it is called before the cursor moves past the
+// block, so that a crash in between leaves a block re-polled rather than missing from the audit
+func (p *Poller) markComplete(block uint64) {
+	if p.progress == nil {
+		return
+	}
+	//	the block has been written even if the poller is stopping, so don't tie the write to the poller's context
+	if err := p.progress.MarkComplete(context.Background(), p.progressKey, block); err != nil {
+		p.logger.Errorf("Failed to record completion of block %d: %v", block, err)
+		p.metrics.Incr(fmt.Sprintf("%s-poller-progress-errors", p.cfg.Blockchain), []string{}, 1.0)
+	}
+}
+
+// awaitBatch waits for every block of a batch of consecutive blocks to complete, recording completed blocks and
+// logging failed ones; returns false if any block was abandoned or turned away by Pause() or an open circuit breaker,
+// in which case the cursor must not advance past the batch
+func (p *Poller) awaitBatch(from uint64, receipts []*pipeline.Receipt) bool {
+	complete, tripped := true, false
+	for i, receipt := range receipts {
+		err := receipt.Wait()
+		if err == nil {
+			p.markComplete(from + uint64(i))
+			continue
+		}
+		if errors.Is(err, pool.ErrJobAbandoned) || errors.Is(err, pool.ErrPoolDraining) {
+			complete = false
+			continue
+		}
+		if errors.Is(err, circuitbreaker.ErrOpen) {
+			complete = false
+			tripped = true
+			continue
+		}
+		p.logger.Errorf("Error processing block: %v", err)
+	}
+	switch {
+	case tripped:
+		p.logger.Warn("Batch refused by open circuit breaker; the cursor will not advance")
+	case !complete:
+		p.logger.Warn("Batch interrupted by pause; the cursor will not advance")
+	}
+	return complete
+}
+
+// breakerOpen reports whether the circuit breaker, if any, is refusing calls to the node
+func (p *Poller) breakerOpen() bool {
+	return p.breaker != nil && p.breaker.State() == circuitbreaker.Open
+}
+
+// guard makes a call to the node through the circuit breaker, if any
+func (p *Poller) guard(fn retry.RunnerFunc) error {
+	if p.breaker == nil {
+		return fn()
+	}
+	return p.breaker.Execute(fn)
+}
+
+// retryPolicy retries calls to the node and the cache up to the configured number of times, backing off
+// exponentially; retries stop as soon as the poller does
+func (p *Poller) retryPolicy() retry.Policy {
+	policy := retry.Exponential(time.Second, 30*time.Second)
+	policy.MaxAttempts = p.cfg.HttpRetries
+	policy.OnRetry = func(attempt int, err error, delay time.Duration) {
+		p.logger.Warnf("Attempt %d failed, retrying in %v: %v", attempt, delay, err)
+		p.metrics.Incr(fmt.Sprintf("%s-poller-retries", p.cfg.Blockchain), []string{}, 1.0)
+	}
+	return policy
+}
@@ -0,0 +1,98 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/circuitbreaker"
+	"github.com/coherentopensource/go-service-framework/pool"
+)
+
+// blockOutcome is the result of polling a single block of the sliding window
+type blockOutcome struct {
+	block uint64
+	err   error
+}
+
+// runWindow polls blocks from the cursor up to the limit, keeping WindowSize blocks in flight at all times rather
+// than waiting on whole batches. Completions are tracked per block, and the cursor only ever advances to the end of
+// the contiguous run of completed blocks, so blocks still in flight, or interrupted, are re-polled after a restart.
+// A failed block is re-polled up to HttpRetries times; if it still fails, the window drains and the poller sleeps,
+// leaving the cursor at the failed block. The cursor is persisted every tick; returns the final cursor once the
+// window has run dry, which happens early if the poller is paused or stopped, a block keeps failing, or a block is
+// turned away by an open circuit breaker
+func (p *Poller) runWindow(ctx context.Context, cursor, limit uint64) uint64 {
+	outcomeCh := make(chan blockOutcome, p.cfg.WindowSize)
+	completed := map[uint64]bool{}
+	attempts := map[uint64]int{}
+	next, inFlight := cursor, 0
+	stopping, failed := false, false
+	lastPersisted := time.Now()
+
+	submit := func(block uint64) {
+		receipt := p.submitBlock(ctx, block)
+		attempts[block]++
+		inFlight++
+		go func() {
+			outcomeCh <- blockOutcome{block: block, err: receipt.Wait()}
+		}()
+	}
+
+	for {
+		//	Top up the window
+		for !stopping && inFlight < p.cfg.WindowSize && next < limit && ctx.Err() == nil && p.mode == ModeBackfill {
+			submit(next)
+			next++
+		}
+		if inFlight == 0 {
+			break
+		}
+
+		outcome := <-outcomeCh
+		inFlight--
+		switch {
+		case errors.Is(outcome.err, pool.ErrJobAbandoned) || errors.Is(outcome.err, pool.ErrPoolDraining):
+			p.logger.Warnf("Block %d interrupted by pause; the window will drain", outcome.block)
+			stopping = true
+			continue
+		case errors.Is(outcome.err, circuitbreaker.ErrOpen):
+			p.logger.Warnf("Block %d refused by open circuit breaker; the window will drain", outcome.block)
+			stopping = true
+			continue
+		case outcome.err != nil:
+			p.logger.Errorf("Error processing block %d (attempt %d): %v", outcome.block, attempts[outcome.block], outcome.err)
+			if !stopping && attempts[outcome.block] < p.cfg.HttpRetries && ctx.Err() == nil {
+				submit(outcome.block)
+				continue
+			}
+			//	The cursor can never advance past a block that keeps failing, so stop topping up the window
+			if !failed {
+				p.logger.Warnf("Block %d failed %d times; the window will drain", outcome.block, attempts[outcome.block])
+			}
+			stopping, failed = true, true
+			continue
+		}
+
+		//	Advance the cursor past every contiguous completed block
+		p.markComplete(outcome.block)
+		delete(attempts, outcome.block)
+		completed[outcome.block] = true
+		for completed[cursor] {
+			delete(completed, cursor)
+			cursor++
+		}
+		if time.Since(lastPersisted) >= p.cfg.Tick {
+			if err := p.setCurrentChaintip(ctx, cursor); err != nil {
+				p.logger.Errorf("failed to update block chain tip within redis: %v", err)
+			} else {
+				p.reportProgress(cursor)
+			}
+			lastPersisted = time.Now()
+		}
+	}
+	if failed && p.mode == ModeBackfill {
+		p.setSleepMode()
+	}
+	return cursor
+}
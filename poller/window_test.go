@@ -0,0 +1,107 @@
+package poller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRunWindow(t *testing.T) {
+	//	setup returns a poller in backfill mode with a window of 4 blocks, retrying failed blocks up to 3 times
+	setup := func(t *testing.T) (*Poller, *fakeDriver, *fakeCache, context.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		t.Cleanup(cancel)
+
+		ev := newEvents()
+		driver := newFakeDriver(ev, 1000)
+		c := newFakeCache(ev)
+		cfg := testConfig()
+		cfg.WindowSize = 4
+		cfg.HttpRetries = 3
+		p := newTestPoller(t, cfg, driver, c)
+		p.runCtx = ctx
+		p.mode = ModeBackfill
+		return p, driver, c, ctx
+	}
+	expectWritten := func(t *testing.T, driver *fakeDriver, from, to uint64) {
+		for block := from; block < to; block++ {
+			if writes := driver.writes(block); writes != 1 {
+				t.Errorf("Expected block %d to be written once, got %d", block, writes)
+			}
+		}
+	}
+
+	t.Run("out of order completions", func(t *testing.T) {
+		p, driver, c, ctx := setup(t)
+		driver.delays[10] = 50 * time.Millisecond
+		driver.delays[12] = 20 * time.Millisecond
+		driver.failures[13] = 2
+
+		if cursor := p.runWindow(ctx, 10, 20); cursor != 20 {
+			t.Errorf("Expected the window to reach block 20, got %d", cursor)
+		}
+		expectWritten(t, driver, 10, 20)
+		//	the cursor only ever moves forward, and never past a block that has yet to be written
+		written, last := map[uint64]bool{}, uint64(10)
+		for _, event := range c.events.all() {
+			var block uint64
+			if _, err := fmt.Sscanf(event, "write:%d", &block); err == nil {
+				written[block] = true
+				continue
+			}
+			if _, err := fmt.Sscanf(event, "cursor:%d", &block); err != nil {
+				continue
+			}
+			if block < last {
+				t.Errorf("Expected the cursor to only move forward, went from %d to %d", last, block)
+			}
+			for pending := uint64(10); pending < block; pending++ {
+				if !written[pending] {
+					t.Errorf("Expected the cursor to stay behind block %d until written, got %d", pending, block)
+				}
+			}
+			last = block
+		}
+	})
+
+	t.Run("failing block", func(t *testing.T) {
+		p, driver, c, ctx := setup(t)
+		p.cfg.SleepTime = time.Hour
+		driver.failures[12] = 3
+
+		if cursor := p.runWindow(ctx, 10, 20); cursor != 12 {
+			t.Errorf("Expected the cursor to stop at the failed block 12, got %d", cursor)
+		}
+		if writes := driver.writes(12); writes != 0 {
+			t.Errorf("Expected block 12 never to be written, got %d writes", writes)
+		}
+		if cursor, ok := c.cursor(p.cacheKey()); ok && cursor > 12 {
+			t.Errorf("Expected the persisted cursor not to pass block 12, got %d", cursor)
+		}
+		if p.Mode() != ModeSleep {
+			t.Errorf("Expected the poller to sleep after giving up on a block, got mode %s", modeToString(p.Mode()))
+		}
+	})
+
+	t.Run("drain", func(t *testing.T) {
+		p, driver, c, ctx := setup(t)
+		for block := uint64(10); block < 1000; block++ {
+			driver.delays[block] = 5 * time.Millisecond
+		}
+
+		//	drain the pipeline as Pause() does, which turns away the blocks submitted in the meantime
+		go func() {
+			time.Sleep(30 * time.Millisecond)
+			p.pipeline.Drain(ctx)
+		}()
+		cursor := p.runWindow(ctx, 10, 1000)
+		if cursor >= 1000 {
+			t.Fatalf("Expected the drain to stop the window early, got cursor %d", cursor)
+		}
+		expectWritten(t, driver, 10, cursor)
+		if persisted, ok := c.cursor(p.cacheKey()); ok && persisted > cursor {
+			t.Errorf("Expected the persisted cursor %d not to pass the final cursor %d", persisted, cursor)
+		}
+	})
+}
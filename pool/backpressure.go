@@ -0,0 +1,313 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BackpressurePolicy determines what a pool does with a result when its results channel is full, i.e. when the
+// consumer of Results() (typically a downstream pool fed via SetInputFeed()) is not keeping up
+type BackpressurePolicy int
+
+const (
+	//	Block holds the worker that produced the result until the consumer makes room; this is the default
+	Block BackpressurePolicy = iota
+	//	DropOldest discards the oldest buffered result to make room, releasing its receipt
+	DropOldest
+	//	Spill writes the result to disk, to be delivered in order once the consumer catches up
+	Spill
+)
+
+var (
+	// ErrResultDropped is reported for results discarded by the DropOldest backpressure policy
+	ErrResultDropped = errors.New("result dropped")
+)
+
+// Backpressure configures the handling of a full results channel, enabled with WithBackpressure()
+type Backpressure struct {
+	Policy BackpressurePolicy
+	//	DropWeight is the number of receipt signals a discarded result stands for, i.e. the number of jobs that
+	//	downstream pools would have run for it, and which were added to the receipt up front; required by DropOldest
+	//	(Start() fails without it, as dropped results would hold their receipts open forever), and used by Spill for
+	//	payloads that can't be read back
+	DropWeight int
+	//	Codec serializes spilled payloads; required by Spill
+	Codec Codec
+	//	SpillDir is the directory of the spill file; defaults to the OS temp directory
+	SpillDir string
+}
+
+// Codec serializes result payloads for the Spill backpressure policy
+type Codec interface {
+	Encode(payload interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+// JSONCodec is a Codec for payloads of type T, which are decoded back into a T
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(payload interface{}) ([]byte, error) {
+	return json.Marshal(payload)
+}
+
+func (JSONCodec[T]) Decode(data []byte) (interface{}, error) {
+	var out T
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// spill is an on-disk FIFO of results; only the payloads are written to disk, while receipts, priorities and trace
+// contexts are kept in memory
+type spill struct {
+	mu       *sync.Mutex
+	file     *os.File
+	offset   int64
+	queue    []spilledResult
+	notifyCh chan struct{}
+}
+
+type spilledResult struct {
+	offset   int64
+	size     int
+	wg       *sync.WaitGroup
+	priority Priority
+	traceCtx trace.SpanContext
+}
+
+func newSpill() *spill {
+	return &spill{mu: &sync.Mutex{}, notifyCh: make(chan struct{}, 1)}
+}
+
+// publish hands a result to the consumer of Results(), applying the pool's backpressure policy if the results
+// channel is full; once the pool is stopping, a result that can't be delivered is abandoned rather than holding its
+// worker, which Stop() waits for
+func (wp *WorkerPool) publish(res result) {
+	done := wp.stopping()
+	switch wp.backpressure.Policy {
+	case DropOldest:
+		for {
+			select {
+			case wp.resultCh <- res:
+				return
+			case <-done:
+				wp.drop(res, ErrJobAbandoned)
+				return
+			default:
+			}
+			select {
+			case dropped := <-wp.resultCh:
+				wp.drop(dropped, ErrResultDropped)
+			default:
+			}
+		}
+	case Spill:
+		if err := wp.spillResult(res); err != nil {
+			wp.logger.Errorf("Failed to spill result of worker pool [%s], blocking instead: %v", wp.id, err)
+			wp.deliver(res, done)
+		}
+	default:
+		wp.deliver(res, done)
+	}
+}
+
+// deliver blocks until the consumer of Results() takes a result, or the pool stops, in which case the result is
+// abandoned
+func (wp *WorkerPool) deliver(res result, done <-chan struct{}) {
+	select {
+	case wp.resultCh <- res:
+	case <-done:
+		wp.drop(res, ErrJobAbandoned)
+	}
+}
+
+// stopping returns a channel that is closed once the running pool stops; nil (blocking forever) if it never started
+func (wp *WorkerPool) stopping() <-chan struct{} {
+	wp.scaleMu.Lock()
+	defer wp.scaleMu.Unlock()
+	if wp.runCtx == nil {
+		return nil
+	}
+	return wp.runCtx.Done()
+}
+
+// drop discards a result, releasing its receipt on behalf of the downstream jobs it stood for
+func (wp *WorkerPool) drop(res result, cause error) {
+	wp.backpressureMu.Lock()
+	wp.countDropped++
+	wp.backpressureMu.Unlock()
+
+	wp.reportErr(fmt.Errorf("result of worker pool [%s]: %w", wp.id, cause))
+	if wp.metrics != nil {
+		wp.metrics.Incr("worker_pool.results_dropped", []string{fmt.Sprintf("pool:%s", wp.id)}, 1.0)
+	}
+	for i := 0; i < wp.backpressure.DropWeight; i++ {
+		res.wg.Done()
+	}
+}
+
+// spillResult sends a result straight to the results channel if it has room and nothing is spilled already;
+// otherwise, the result is appended to the spill, preserving the order of results
+func (wp *WorkerPool) spillResult(res result) error {
+	s := wp.spill
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) == 0 {
+		select {
+		case wp.resultCh <- res:
+			return nil
+		default:
+		}
+	}
+
+	data, err := wp.backpressure.Codec.Encode(res.payload)
+	if err != nil {
+		return err
+	}
+	if s.file == nil {
+		s.file, err = os.CreateTemp(wp.backpressure.SpillDir, fmt.Sprintf("pool-%s-*.spill", wp.id))
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := s.file.WriteAt(data, s.offset); err != nil {
+		return err
+	}
+	s.queue = append(s.queue, spilledResult{offset: s.offset, size: len(data), wg: res.wg, priority: res.priority, traceCtx: res.traceCtx})
+	s.offset += int64(len(data))
+
+	if wp.metrics != nil {
+		wp.metrics.Incr("worker_pool.results_spilled", []string{fmt.Sprintf("pool:%s", wp.id)}, 1.0)
+	}
+	select {
+	case s.notifyCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// startSpillDrainer spins up a worker that moves spilled results back into the results channel, oldest first, if the
+// Spill backpressure policy has been specified
+func (wp *WorkerPool) startSpillDrainer(ctx context.Context) {
+	if wp.backpressure.Policy != Spill {
+		return
+	}
+
+	wp.workerWg.Add(1)
+	go func() {
+		defer wp.workerWg.Done()
+		for {
+			res, ok, err := wp.peekSpill()
+			switch {
+			case err != nil:
+				wp.popSpill()
+				wp.drop(res, err)
+				continue
+			case !ok:
+				select {
+				case <-ctx.Done():
+					return
+				case <-wp.spill.notifyCh:
+				}
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case wp.resultCh <- res:
+				wp.popSpill()
+			}
+		}
+	}()
+}
+
+// peekSpill reads back the oldest spilled result, if any
+func (wp *WorkerPool) peekSpill() (result, bool, error) {
+	s := wp.spill
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return result{}, false, nil
+	}
+
+	head := s.queue[0]
+	res := result{wg: head.wg, priority: head.priority, traceCtx: head.traceCtx}
+	data := make([]byte, head.size)
+	if _, err := s.file.ReadAt(data, head.offset); err != nil {
+		return res, true, fmt.Errorf("reading spilled result: %w", err)
+	}
+	payload, err := wp.backpressure.Codec.Decode(data)
+	if err != nil {
+		return res, true, fmt.Errorf("decoding spilled result: %w", err)
+	}
+	res.payload = payload
+	return res, true, nil
+}
+
+// popSpill removes the oldest spilled result, reclaiming the spill file once it is empty
+func (wp *WorkerPool) popSpill() {
+	s := wp.spill
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = s.queue[1:]
+	if len(s.queue) == 0 {
+		s.offset = 0
+		if err := s.file.Truncate(0); err != nil {
+			wp.logger.Errorf("Failed to truncate spill file of worker pool [%s]: %v", wp.id, err)
+		}
+	}
+}
+
+// spilled counts the results waiting in the spill
+func (wp *WorkerPool) spilled() int {
+	wp.spill.mu.Lock()
+	defer wp.spill.mu.Unlock()
+	return len(wp.spill.queue)
+}
+
+// abandonResults drops the results left in the results channel when the pool stops, releasing their receipts on
+// behalf of the downstream jobs they stood for (see DropWeight) and reporting them as abandoned
+func (wp *WorkerPool) abandonResults() {
+	for {
+		select {
+		case res := <-wp.resultCh:
+			wp.drop(res, ErrJobAbandoned)
+			continue
+		default:
+		}
+		return
+	}
+}
+
+// closeSpill removes the spill file; results still spilled are dropped, as by abandonResults()
+func (wp *WorkerPool) closeSpill() {
+	s := wp.spill
+	s.mu.Lock()
+	if s.file == nil {
+		s.mu.Unlock()
+		return
+	}
+	queue := s.queue
+	s.file.Close()
+	os.Remove(s.file.Name())
+	s.file = nil
+	s.queue = nil
+	s.offset = 0
+	s.mu.Unlock()
+
+	if len(queue) > 0 {
+		wp.logger.Warnf("Worker pool [%s] stopped with %d results still spilled", wp.id, len(queue))
+	}
+	for _, spilled := range queue {
+		wp.drop(result{wg: spilled.wg, priority: spilled.priority, traceCtx: spilled.traceCtx}, ErrJobAbandoned)
+	}
+}
@@ -0,0 +1,240 @@
+package pool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/cache"
+)
+
+// DeadLetter records a job that failed permanently, i.e. after exhausting its retries
+type DeadLetter struct {
+	JobID      string    `json:"job_id"`
+	GroupID    string    `json:"group_id,omitempty"`
+	PoolID     string    `json:"pool_id"`
+	Error      string    `json:"error"`
+	Attempts   int       `json:"attempts"`
+	Descriptor string    `json:"descriptor,omitempty"`
+	FailedAt   time.Time `json:"failed_at"`
+}
+
+// DeadLetterSink stores dead-lettered jobs for later inspection or replay
+type DeadLetterSink interface {
+	//	Put stores a dead-lettered job
+	Put(ctx context.Context, entry *DeadLetter) error
+	//	Drain removes and returns every stored entry; entries that can't be decoded are kept in the sink and reported
+	//	in the error, alongside the entries that could be
+	Drain(ctx context.Context) ([]*DeadLetter, error)
+}
+
+// ReplayResolver rebuilds the Runner of a dead-lettered job, typically from its descriptor
+type ReplayResolver func(entry *DeadLetter) (Runner, error)
+
+// Replay drains a dead-letter sink and pushes every entry back into the pool as a one-off job, returning the number of
+// jobs replayed; entries that cannot be resolved are put back into the sink. Entries drained before the sink failed
+// are still replayed, with the error of the sink returned alongside. As with PushJob, the caller is responsible for
+// adding the receipt load of each replayed job to wg, e.g. from within resolve
+func (wp *WorkerPool) Replay(ctx context.Context, sink DeadLetterSink, resolve ReplayResolver, wg *sync.WaitGroup) (int, error) {
+	entries, err := sink.Drain(ctx)
+	var errs []error
+	if err != nil {
+		wp.logger.Errorf("Failed to drain dead-letter sink of pool [%s]; replaying the %d entries drained: %v", wp.id, len(entries), err)
+		errs = append(errs, err)
+	}
+
+	replayed := 0
+	for _, entry := range entries {
+		fn, err := resolve(entry)
+		if err != nil {
+			wp.logger.Errorf("Failed to resolve dead-lettered job [%s] for replay in pool [%s]: %v", entry.JobID, wp.id, err)
+			if err := sink.Put(ctx, entry); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		wp.PushJob(fn, wg, WithDescriptor(entry.Descriptor))
+		replayed++
+	}
+
+	return replayed, errors.Join(errs...)
+}
+
+// deadLetter records a permanently failed job in the pool's dead-letter sink, if one is configured
+func (wp *WorkerPool) deadLetter(job *job, err error) {
+	if wp.deadLetterSink == nil {
+		return
+	}
+
+	entry := &DeadLetter{
+		JobID:      job.id,
+		GroupID:    job.groupID,
+		PoolID:     wp.id,
+		Error:      err.Error(),
+		Attempts:   job.attempts,
+		Descriptor: job.descriptor,
+		FailedAt:   time.Now().UTC(),
+	}
+	//	the pool context may already be cancelled when jobs fail during shutdown, so don't tie the write to it
+	if err := wp.deadLetterSink.Put(context.Background(), entry); err != nil {
+		wp.logger.Errorf("Failed to dead-letter job [%s] in pool [%s]: %v", job.id, wp.id, err)
+	}
+}
+
+// MemoryDeadLetterSink keeps dead-lettered jobs in memory
+type MemoryDeadLetterSink struct {
+	mu      *sync.Mutex
+	entries []*DeadLetter
+}
+
+// NewMemoryDeadLetterSink instantiates an empty in-memory sink
+func NewMemoryDeadLetterSink() *MemoryDeadLetterSink {
+	return &MemoryDeadLetterSink{mu: &sync.Mutex{}}
+}
+
+func (s *MemoryDeadLetterSink) Put(ctx context.Context, entry *DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *MemoryDeadLetterSink) Drain(ctx context.Context) ([]*DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := s.entries
+	s.entries = nil
+	return entries, nil
+}
+
+// FileDeadLetterSink appends dead-lettered jobs to a file, one JSON object per line
+type FileDeadLetterSink struct {
+	mu   *sync.Mutex
+	path string
+}
+
+// NewFileDeadLetterSink instantiates a sink writing to the JSONL file at path; the file is created on first write
+func NewFileDeadLetterSink(path string) *FileDeadLetterSink {
+	return &FileDeadLetterSink{mu: &sync.Mutex{}, path: path}
+}
+
+func (s *FileDeadLetterSink) Put(ctx context.Context, entry *DeadLetter) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+func (s *FileDeadLetterSink) Drain(ctx context.Context) ([]*DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.Open(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []*DeadLetter
+	var corrupt [][]byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			corrupt = append(corrupt, append(append([]byte{}, scanner.Bytes()...), '\n'))
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	//	undecodable lines are all that is left in the file
+	if err := os.WriteFile(s.path, bytes.Join(corrupt, nil), 0644); err != nil {
+		return nil, err
+	}
+	if len(corrupt) > 0 {
+		return entries, fmt.Errorf("%d dead-letter entries in %s could not be decoded", len(corrupt), s.path)
+	}
+	return entries, nil
+}
+
+// ListStore pushes and pops the entries of Redis lists; *cache.Cache implements it
+type ListStore interface {
+	RPush(ctx context.Context, key string, values ...interface{}) error
+	LPop(ctx context.Context, key string) (string, error)
+}
+
+// RedisDeadLetterSink pushes dead-lettered jobs onto a Redis list
+type RedisDeadLetterSink struct {
+	cache ListStore
+	key   string
+}
+
+// NewRedisDeadLetterSink instantiates a sink backed by the Redis list at key
+func NewRedisDeadLetterSink(c ListStore, key string) *RedisDeadLetterSink {
+	return &RedisDeadLetterSink{cache: c, key: key}
+}
+
+func (s *RedisDeadLetterSink) Put(ctx context.Context, entry *DeadLetter) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.cache.RPush(ctx, s.key, string(line))
+}
+
+// Drain pops entries until the list is empty; entries that can't be decoded are pushed back once the list has been
+// drained, so that they are neither lost nor popped again by this call
+func (s *RedisDeadLetterSink) Drain(ctx context.Context) ([]*DeadLetter, error) {
+	var entries []*DeadLetter
+	var corrupt []interface{}
+	var popErr error
+	for {
+		line, err := s.cache.LPop(ctx, s.key)
+		if err != nil {
+			var notInCache *cache.NotInRedisCacheError
+			if !errors.As(err, &notInCache) {
+				popErr = err
+			}
+			break
+		}
+		var entry DeadLetter
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			corrupt = append(corrupt, line)
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+
+	if len(corrupt) == 0 {
+		return entries, popErr
+	}
+	if err := s.cache.RPush(ctx, s.key, corrupt...); err != nil {
+		return entries, errors.Join(popErr, fmt.Errorf("restoring %d undecodable dead-letter entries: %w", len(corrupt), err))
+	}
+	return entries, errors.Join(popErr, fmt.Errorf("%d dead-letter entries in %s could not be decoded", len(corrupt), s.key))
+}
@@ -0,0 +1,141 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	drainPollInterval = 10 * time.Millisecond
+)
+
+var (
+	// ErrPoolDraining is reported for jobs pushed while the pool is draining; their receipts are released unrun
+	ErrPoolDraining = errors.New("pool is draining")
+	// ErrJobAbandoned is reported for queued jobs that were never run, because the pool stopped or a drain timed out
+	ErrJobAbandoned = errors.New("job abandoned")
+)
+
+// DrainReport summarizes a call to Drain()
+type DrainReport struct {
+	//	Abandoned lists the queued jobs that were released unrun because the drain deadline passed
+	Abandoned []AbandonedJob
+	//	InFlight is the number of jobs still running (or awaiting a retry) when the drain deadline passed; these
+	//	jobs are not abandoned and will still complete
+	InFlight int
+	//	Rejected is the number of jobs pushed, and turned away, while the pool was draining
+	Rejected int
+}
+
+// Complete reports whether the drain finished all work before its deadline
+func (r *DrainReport) Complete() bool {
+	return len(r.Abandoned) == 0 && r.InFlight == 0
+}
+
+// AbandonedJob identifies a job that was released unrun
+type AbandonedJob struct {
+	JobID      string
+	GroupID    string
+	Descriptor string
+}
+
+// Drain stops the pool from accepting new work via PushJob()/PushGroup(), then waits for every queued and running
+// job to finish, including jobs fed from an input feed; if ctx ends first, the jobs still queued are abandoned
+// (releasing their receipts) and reported. The pool resumes accepting work once Drain returns. When draining chained
+// pools, drain upstream pools first so that their results are still accepted downstream
+func (wp *WorkerPool) Drain(ctx context.Context) *DrainReport {
+	wp.drainMu.Lock()
+	wp.draining = true
+	wp.countRejected = 0
+	wp.drainMu.Unlock()
+
+	report := &DrainReport{}
+	defer func() {
+		wp.drainMu.Lock()
+		report.Rejected = wp.countRejected
+		wp.draining = false
+		wp.drainMu.Unlock()
+	}()
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for !wp.idle() {
+		select {
+		case <-ctx.Done():
+			report.Abandoned = wp.abandonQueued()
+			report.InFlight = wp.pending()
+			wp.logger.Warnf("Drain of worker pool [%s] timed out; %d jobs abandoned, %d still in flight", wp.id, len(report.Abandoned), report.InFlight)
+			return report
+		case <-ticker.C:
+		}
+	}
+
+	wp.logger.Infof("Worker pool [%s] drained", wp.id)
+	return report
+}
+
+// reject turns away pushed jobs while the pool is draining, releasing their receipts; returns true if the jobs
+// were rejected
+func (wp *WorkerPool) reject(jobCount int, wg *sync.WaitGroup, emitter Emitter) bool {
+	wp.drainMu.Lock()
+	defer wp.drainMu.Unlock()
+	if !wp.draining {
+		return false
+	}
+
+	wp.countRejected += jobCount
+	err := fmt.Errorf("%d job(s) pushed to worker pool [%s]: %w", jobCount, wp.id, ErrPoolDraining)
+	wp.reportErr(err)
+	if emitter := wp.emitterFor(emitter); emitter != nil {
+		emitter(Emission{Err: err, Receipt: wg})
+	}
+	for i := 0; i < jobCount; i++ {
+		wg.Done()
+	}
+	return true
+}
+
+// abandonQueued releases every job still queued in the lanes without running it
+func (wp *WorkerPool) abandonQueued() []AbandonedJob {
+	var abandoned []AbandonedJob
+	for _, lane := range wp.lanes {
+		for {
+			select {
+			case job := <-lane:
+				abandoned = append(abandoned, AbandonedJob{JobID: job.id, GroupID: job.groupID, Descriptor: job.descriptor})
+				wp.finish(&job, nil, fmt.Errorf("job [%s] in worker pool [%s]: %w", job.id, wp.id, ErrJobAbandoned))
+				continue
+			default:
+			}
+			break
+		}
+	}
+	return abandoned
+}
+
+// idle reports whether the pool has no pending jobs, nothing waiting in its input feed and no spilled results
+func (wp *WorkerPool) idle() bool {
+	return wp.pending() == 0 && wp.queuedInFeeds() == 0 && wp.spilled() == 0
+}
+
+func (wp *WorkerPool) pending() int {
+	wp.pendingMu.Lock()
+	defer wp.pendingMu.Unlock()
+	return wp.countPending
+}
+
+func (wp *WorkerPool) incrPending(n int) {
+	wp.pendingMu.Lock()
+	wp.countPending += n
+	wp.pendingMu.Unlock()
+}
+
+func (wp *WorkerPool) decrPending() {
+	wp.pendingMu.Lock()
+	wp.countPending--
+	wp.countSettled++
+	wp.pendingMu.Unlock()
+}
@@ -0,0 +1,150 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	defaultFeedName = "default"
+)
+
+// feed is a named input channel of a pool, with the transformers that convert its payloads into jobs
+type feed struct {
+	name         string
+	ch           <-chan result
+	transformers map[string]FeedTransformer
+	group        bool
+	quitCh       chan struct{}
+}
+
+// SetInputFeed configures the workerpool to receive jobs from an input channel, with "transformer" methods
+// that convert a generic input interface into a Runner; this is shorthand for AddInputFeed() under a default name
+func (wp *WorkerPool) SetInputFeed(feed <-chan result, transformers ...FeedTransformer) {
+	if err := wp.AddInputFeed(defaultFeedName, feed, transformers...); err != nil {
+		wp.logger.Warn("Attempting to set input feed, when input feed is already defined")
+	}
+}
+
+// SetGroupInputFeed configures the workerpool to receive jobs from an input channel, with "transformer" methods
+// that convert a generic input interface into a Runner; with the runners executed as a group. This is shorthand for
+// AddGroupInputFeed() under a default name
+func (wp *WorkerPool) SetGroupInputFeed(feed <-chan result, groupMap map[string]FeedTransformer) {
+	if err := wp.AddGroupInputFeed(defaultFeedName, feed, groupMap); err != nil {
+		wp.logger.Warn("Attempting to set group input feed, when input feed is already defined")
+	}
+}
+
+// AddInputFeed attaches a named input channel to the workerpool, with each transformer producing one job per input;
+// a pool may consume any number of feeds, which are merged fairly, and feeds may be added while the pool is running
+func (wp *WorkerPool) AddInputFeed(name string, ch <-chan result, transformers ...FeedTransformer) error {
+	transformerMap := map[string]FeedTransformer{}
+	for _, transformer := range transformers {
+		transformerMap[ksuid.New().String()] = transformer
+	}
+	return wp.addFeed(&feed{name: name, ch: ch, transformers: transformerMap})
+}
+
+// AddGroupInputFeed attaches a named input channel to the workerpool, with the transformers producing the members of
+// a group per input
+func (wp *WorkerPool) AddGroupInputFeed(name string, ch <-chan result, groupMap map[string]FeedTransformer) error {
+	return wp.addFeed(&feed{name: name, ch: ch, transformers: groupMap, group: true})
+}
+
+func (wp *WorkerPool) addFeed(f *feed) error {
+	wp.feedMu.Lock()
+	defer wp.feedMu.Unlock()
+	if _, ok := wp.feeds[f.name]; ok {
+		return fmt.Errorf("input feed [%s] is already defined for worker pool [%s]", f.name, wp.id)
+	}
+	f.quitCh = make(chan struct{})
+	wp.feeds[f.name] = f
+
+	//	feeds added to a running pool start right away; otherwise they start with the pool
+	wp.scaleMu.Lock()
+	runCtx := wp.runCtx
+	wp.scaleMu.Unlock()
+	if runCtx != nil && runCtx.Err() == nil {
+		wp.startFeeder(runCtx, f)
+	}
+	return nil
+}
+
+// DetachFeed stops the workerpool from consuming a named input feed; a payload already taken from the feed is still
+// turned into jobs, and whatever remains in the feed channel is left for another consumer
+func (wp *WorkerPool) DetachFeed(name string) error {
+	wp.feedMu.Lock()
+	defer wp.feedMu.Unlock()
+	f, ok := wp.feeds[name]
+	if !ok {
+		return fmt.Errorf("input feed [%s] is not defined for worker pool [%s]", name, wp.id)
+	}
+	close(f.quitCh)
+	delete(wp.feeds, name)
+	return nil
+}
+
+// startFeeders spins up a feeder for every input feed of the pool
+func (wp *WorkerPool) startFeeders(ctx context.Context) {
+	wp.feedMu.Lock()
+	defer wp.feedMu.Unlock()
+	for _, f := range wp.feeds {
+		wp.startFeeder(ctx, f)
+	}
+}
+
+// startFeeder spins up a worker to read off of a single feed channel; each feed has its own feeder, and feeders
+// blocked on a full lane are served in turn, so no feed can starve the others
+func (wp *WorkerPool) startFeeder(ctx context.Context, f *feed) {
+	wp.workerWg.Add(1)
+	go func() {
+		defer wp.workerWg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-f.quitCh:
+				return
+			case res := <-f.ch:
+				//	hold a pending slot while transforming, so that the pool isn't seen as idle mid-feed
+				wp.incrPending(1)
+				group := map[string]Runner{}
+				for id, transformer := range f.transformers {
+					switch {
+					case f.group:
+						group[id] = wp.transform(transformer, res.payload)
+					default:
+						wp.incrPending(1)
+						wp.enqueue(job{fn: wp.transform(transformer, res.payload), receiptWg: res.wg, id: ksuid.New().String(), priority: res.priority, traceCtx: res.traceCtx})
+					}
+				}
+				if f.group {
+					wp.pushGroup(group, res.wg, pushConfig{priority: res.priority, traceCtx: res.traceCtx})
+				}
+				wp.decrPending()
+			}
+		}
+	}()
+}
+
+// feedDepths reports the number of payloads waiting in each input feed
+func (wp *WorkerPool) feedDepths() map[string]int {
+	wp.feedMu.Lock()
+	defer wp.feedMu.Unlock()
+	depths := make(map[string]int, len(wp.feeds))
+	for name, f := range wp.feeds {
+		depths[name] = len(f.ch)
+	}
+	return depths
+}
+
+// queuedInFeeds counts the payloads waiting across every input feed
+func (wp *WorkerPool) queuedInFeeds() int {
+	total := 0
+	for _, depth := range wp.feedDepths() {
+		total += depth
+	}
+	return total
+}
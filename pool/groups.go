@@ -0,0 +1,79 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GroupPolicy determines how a group handles errors from its members
+type GroupPolicy int
+
+const (
+	//	CollectAll runs every member of the group, then reports a GroupError carrying every member error; nothing is
+	//	forwarded downstream if any member failed
+	CollectAll GroupPolicy = iota
+	//	FailFast cancels the remaining members of the group on the first error, then reports a GroupError carrying
+	//	that error; nothing is forwarded downstream
+	FailFast
+	//	BestEffort runs every member of the group, then forwards a PartialResultSet downstream (and reports a
+	//	GroupError) if any member failed. It must be opted into (see WithDefaultGroupPolicy() and WithGroupPolicy()) by
+	//	pools whose downstream transformers accept a PartialResultSet as well as a ResultSet, since a transformer that
+	//	asserts a ResultSet would panic on the partial one; typed stages decode both
+	BestEffort
+)
+
+var (
+	// ErrGroupCancelled is returned for members of a FailFast group that were cancelled because a sibling failed
+	ErrGroupCancelled = errors.New("group cancelled after a sibling job failed")
+	// ErrGroupNotFound is returned for members of a group the pool no longer tracks, e.g. after FlushAndRestart()
+	ErrGroupNotFound = errors.New("group not found")
+)
+
+// GroupError carries the errors of the failed members of a group, keyed by job name
+type GroupError struct {
+	GroupID string
+	Errors  map[string]error
+}
+
+func (e *GroupError) Error() string {
+	keys := make([]string, 0, len(e.Errors))
+	for key := range e.Errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	msgs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", key, e.Errors[key]))
+	}
+	return fmt.Sprintf("group [%s] failed: %s", e.GroupID, strings.Join(msgs, "; "))
+}
+
+// Unwrap exposes the member errors to errors.Is and errors.As
+func (e *GroupError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// PartialResultSet is forwarded downstream by BestEffort groups in which some members failed
+type PartialResultSet struct {
+	Results ResultSet
+	Errors  map[string]error
+}
+
+// groupContext returns the context shared by the members of a group, which is cancelled when a FailFast group fails
+// or the pool stops; returns nil if the pool no longer tracks the group
+func (wp *WorkerPool) groupContext(groupID string) context.Context {
+	wp.groupMu.Lock()
+	defer wp.groupMu.Unlock()
+	if group, ok := wp.groups[groupID]; ok {
+		return group.ctx
+	}
+	return nil
+}
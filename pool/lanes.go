@@ -0,0 +1,118 @@
+package pool
+
+import (
+	"context"
+)
+
+// Priority determines which lane a job is queued in; workers drain higher lanes first, subject to lane weights
+type Priority int
+
+const (
+	PriorityLow Priority = iota - 1
+	PriorityNormal
+	PriorityHigh
+)
+
+const (
+	laneCount = 3
+)
+
+// defaultLaneWeights gives high-priority jobs the majority of dispatches, while guaranteeing lower lanes a share
+var defaultLaneWeights = map[Priority]int{
+	PriorityHigh:   6,
+	PriorityNormal: 3,
+	PriorityLow:    1,
+}
+
+// lane maps a priority onto an index into the pool's lanes, clamping unknown priorities
+func (p Priority) lane() int {
+	switch {
+	case p <= PriorityLow:
+		return 0
+	case p >= PriorityHigh:
+		return 2
+	default:
+		return 1
+	}
+}
+
+func (p Priority) String() string {
+	switch p.lane() {
+	case 0:
+		return "low"
+	case 2:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// laneFor returns the lane a job should be queued in
+func (wp *WorkerPool) laneFor(job *job) chan job {
+	return wp.lanes[job.priority.lane()]
+}
+
+// queueDepth reports the number of jobs queued across all lanes
+func (wp *WorkerPool) queueDepth() int {
+	depth := 0
+	for _, lane := range wp.lanes {
+		depth += len(lane)
+	}
+	return depth
+}
+
+// startDispatcher spins up a worker that moves jobs from the priority lanes to the job workers, using smooth weighted
+// round-robin between non-empty lanes so that higher lanes are preferred without starving lower ones
+func (wp *WorkerPool) startDispatcher(ctx context.Context) {
+	weights := [laneCount]int{}
+	for priority, weight := range wp.laneWeights {
+		weights[priority.lane()] = weight
+	}
+
+	wp.workerWg.Add(1)
+	go func() {
+		defer wp.workerWg.Done()
+		current := [laneCount]int{}
+		for {
+			//	pick the non-empty lane with the highest running weight
+			picked, total := -1, 0
+			for i := laneCount - 1; i >= 0; i-- {
+				if len(wp.lanes[i]) == 0 || weights[i] <= 0 {
+					continue
+				}
+				current[i] += weights[i]
+				total += weights[i]
+				if picked < 0 || current[i] > current[picked] {
+					picked = i
+				}
+			}
+
+			var next job
+			if picked >= 0 {
+				current[picked] -= total
+				//	a draining pool may have abandoned the job in the meantime
+				select {
+				case next = <-wp.lanes[picked]:
+				default:
+					continue
+				}
+			} else {
+				//	all lanes are empty (or unweighted); take whichever job arrives first
+				select {
+				case <-ctx.Done():
+					return
+				case next = <-wp.lanes[2]:
+				case next = <-wp.lanes[1]:
+				case next = <-wp.lanes[0]:
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				wp.finish(&next, nil, ctx.Err())
+				return
+			case wp.jobCh <- next:
+			}
+		}
+	}()
+}
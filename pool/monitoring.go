@@ -12,6 +12,18 @@ func (wp *WorkerPool) decrInProgress() {
 	wp.inProgressMu.Unlock()
 }
 
+func (wp *WorkerPool) incrAbandoned() {
+	wp.inProgressMu.Lock()
+	wp.countAbandoned++
+	wp.inProgressMu.Unlock()
+}
+
+func (wp *WorkerPool) decrAbandoned() {
+	wp.inProgressMu.Lock()
+	wp.countAbandoned--
+	wp.inProgressMu.Unlock()
+}
+
 func (wp *WorkerPool) incrWaiting() {
 	wp.waitingMu.Lock()
 	wp.countWaiting++
@@ -25,15 +37,66 @@ func (wp *WorkerPool) decrWaiting() {
 }
 
 func (wp *WorkerPool) Insights() map[string]int {
+	//	feeds are read first, as feeds are added under feedMu while scaleMu is acquired
+	feedDepths := wp.feedDepths()
+	feedTotal := 0
+	for _, depth := range feedDepths {
+		feedTotal += depth
+	}
+
 	wp.groupMu.Lock()
 	defer wp.groupMu.Unlock()
-	return map[string]int{
+	wp.retryMu.Lock()
+	defer wp.retryMu.Unlock()
+	wp.inProgressMu.Lock()
+	defer wp.inProgressMu.Unlock()
+	wp.waitingMu.Lock()
+	defer wp.waitingMu.Unlock()
+	wp.scaleMu.Lock()
+	defer wp.scaleMu.Unlock()
+	wp.pendingMu.Lock()
+	defer wp.pendingMu.Unlock()
+	wp.backpressureMu.Lock()
+	defer wp.backpressureMu.Unlock()
+	stalled := 0
+	if wp.stalled {
+		stalled = 1
+	}
+	insights := map[string]int{
 		"bandwidth":  wp.bandwidth,
 		"inProgress": wp.countInProgress,
+		"abandoned":  wp.countAbandoned,
 		"waiting":    wp.countWaiting,
-		"jobCh":      len(wp.jobCh),
+		"jobCh":      wp.queueDepth(),
 		"errCh":      len(wp.errCh),
-		"feedCh":     len(wp.feedCh),
+		"feedCh":     feedTotal,
 		"groups":     len(wp.groups),
+		"retries":    wp.countRetries,
+		"retrying":   wp.countRetrying,
+		"pending":    wp.countPending,
+		"resultCh":   len(wp.resultCh),
+		"dropped":    wp.countDropped,
+		"spilled":    wp.spilled(),
+		"stalled":    stalled,
+	}
+	for _, priority := range []Priority{PriorityHigh, PriorityNormal, PriorityLow} {
+		insights["lane:"+priority.String()] = len(wp.lanes[priority.lane()])
+	}
+	for name, depth := range feedDepths {
+		insights["feed:"+name] = depth
 	}
+	return insights
+}
+
+func (wp *WorkerPool) incrRetrying() {
+	wp.retryMu.Lock()
+	wp.countRetries++
+	wp.countRetrying++
+	wp.retryMu.Unlock()
+}
+
+func (wp *WorkerPool) decrRetrying() {
+	wp.retryMu.Lock()
+	wp.countRetrying--
+	wp.retryMu.Unlock()
 }
@@ -0,0 +1,54 @@
+package pool
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/util"
+)
+
+const (
+	defaultJobKey = "job"
+)
+
+// observe reports the queue wait, execution duration and outcome of a job attempt to the metrics client of the pool,
+// if any, tagged by pool ID and job key; throughput is the rate of the worker_pool.jobs count
+func (wp *WorkerPool) observe(job *job, queueWait time.Duration, duration time.Duration, err error) {
+	if wp.metrics == nil {
+		return
+	}
+
+	tags := wp.jobTags(job)
+	wp.timing("worker_pool.queue_wait", queueWait, tags)
+	wp.timing("worker_pool.job_duration", duration, tags)
+
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	wp.metrics.Incr("worker_pool.jobs", append(tags, fmt.Sprintf("status:%s", status)), 1.0)
+}
+
+// observeThrottle reports the time a job spent waiting for the pool's throttler
+func (wp *WorkerPool) observeThrottle(job *job, wait time.Duration) {
+	if wp.metrics == nil {
+		return
+	}
+	wp.timing("worker_pool.throttle_wait", wait, wp.jobTags(job))
+}
+
+// timing reports a duration if the metrics client of the pool supports distributions
+func (wp *WorkerPool) timing(name string, value time.Duration, tags []string) {
+	if distributions, ok := wp.metrics.(util.DistributionMetrics); ok {
+		distributions.Timing(name, value, tags, 1.0)
+	}
+}
+
+// jobTags tags the metrics of a job with the pool ID and job key
+func (wp *WorkerPool) jobTags(job *job) []string {
+	key := job.key
+	if key == "" {
+		key = defaultJobKey
+	}
+	return []string{fmt.Sprintf("pool:%s", wp.id), fmt.Sprintf("job:%s", key)}
+}
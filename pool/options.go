@@ -1,6 +1,13 @@
 package pool
 
-import "github.com/coherentopensource/go-service-framework/util"
+import (
+	"context"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/retry"
+	"github.com/coherentopensource/go-service-framework/util"
+	"go.opentelemetry.io/otel/trace"
+)
 
 type opt func(wp *WorkerPool)
 
@@ -11,8 +18,9 @@ func WithOutputChannel() opt {
 	}
 }
 
-// WithThrottler specifies a throttler for controlling workload
-func WithThrottler(tt *Throttler) opt {
+// WithThrottler specifies a throttler for controlling workload; every job waits for a token from the throttler before
+// it runs
+func WithThrottler(tt Limiter) opt {
 	return func(wp *WorkerPool) {
 		wp.throttler = tt
 	}
@@ -31,3 +39,169 @@ func WithLogger(logger util.Logger) opt {
 		wp.logger = logger
 	}
 }
+
+// WithMetrics specifies a metrics client for the pool to report to
+func WithMetrics(metrics util.Metrics) opt {
+	return func(wp *WorkerPool) {
+		wp.metrics = metrics
+	}
+}
+
+// WithErrHandler overrides the default error handler, which logs errors
+func WithErrHandler(handler ErrHandler) opt {
+	return func(wp *WorkerPool) {
+		wp.errHandler = handler
+	}
+}
+
+// WithDefaultJobTimeout bounds the execution of every job in the pool, unless overridden per push with WithJobTimeout()
+func WithDefaultJobTimeout(timeout time.Duration) opt {
+	return func(wp *WorkerPool) {
+		wp.jobTimeout = timeout
+	}
+}
+
+// WithJobRetry re-enqueues failed jobs according to the supplied retry policy, e.g. DefaultRetryPolicy(); errors
+// wrapped with retry.Permanent() are not retried, and a policy bounded neither by MaxAttempts nor by MaxElapsed is
+// limited to 3 attempts
+func WithJobRetry(policy retry.Policy) opt {
+	return func(wp *WorkerPool) {
+		policy = retryPolicyOf(policy)
+		wp.retryPolicy = &policy
+	}
+}
+
+// WithDeadLetterSink records permanently failed jobs in the supplied sink
+func WithDeadLetterSink(sink DeadLetterSink) opt {
+	return func(wp *WorkerPool) {
+		wp.deadLetterSink = sink
+	}
+}
+
+// WithDefaultGroupPolicy sets how groups handle member errors, unless overridden per push with WithGroupPolicy(); the
+// default is CollectAll
+func WithDefaultGroupPolicy(policy GroupPolicy) opt {
+	return func(wp *WorkerPool) {
+		wp.groupPolicy = policy
+	}
+}
+
+// WithAutoscaler lets the pool grow and shrink its bandwidth according to its workload
+func WithAutoscaler(cfg AutoscalerConfig) opt {
+	return func(wp *WorkerPool) {
+		wp.autoscaler = &cfg
+	}
+}
+
+// WithLaneWeights overrides the relative share of dispatches each priority lane gets while it has queued jobs; a lane
+// with a weight of 0 is only dispatched from when every other lane is empty
+func WithLaneWeights(weights map[Priority]int) opt {
+	return func(wp *WorkerPool) {
+		wp.laneWeights = weights
+	}
+}
+
+// WithBufferSize sets the capacity of each priority lane and of the results channel, independently of the bandwidth;
+// defaults to the bandwidth
+func WithBufferSize(size int) opt {
+	return func(wp *WorkerPool) {
+		wp.bufferSize = size
+	}
+}
+
+// WithBackpressure sets how the pool handles a full results channel; the default is to Block
+func WithBackpressure(backpressure Backpressure) opt {
+	return func(wp *WorkerPool) {
+		wp.backpressure = backpressure
+	}
+}
+
+// WithStallDetector logs and reports metrics when the pool has work but has made no progress for the given duration
+func WithStallDetector(threshold time.Duration) opt {
+	return func(wp *WorkerPool) {
+		wp.stallThreshold = threshold
+	}
+}
+
+// WithTracer starts an OpenTelemetry span for every job attempt, as a child of the trace context the job was pushed
+// with (see WithTraceContext()); results carry that trace context on to downstream pools
+func WithTracer(tracer trace.Tracer) opt {
+	return func(wp *WorkerPool) {
+		wp.tracer = tracer
+	}
+}
+
+// PushOpt configures the jobs queued by a single PushJob() or PushGroup() call
+type PushOpt func(cfg *pushConfig)
+
+// pushConfig holds the settings applied to the jobs of a single push
+type pushConfig struct {
+	timeout     time.Duration
+	descriptor  string
+	groupPolicy *GroupPolicy
+	priority    Priority
+	emitter     Emitter
+	key         string
+	traceCtx    trace.SpanContext
+}
+
+func newPushConfig(opts []PushOpt) pushConfig {
+	cfg := pushConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithJobTimeout bounds the execution of the pushed job(s), overriding the pool's default timeout
+func WithJobTimeout(timeout time.Duration) PushOpt {
+	return func(cfg *pushConfig) {
+		cfg.timeout = timeout
+	}
+}
+
+// WithDescriptor attaches a caller-supplied description of the pushed job(s) payload, such as a block number, which
+// is recorded if the job is dead-lettered
+func WithDescriptor(descriptor string) PushOpt {
+	return func(cfg *pushConfig) {
+		cfg.descriptor = descriptor
+	}
+}
+
+// WithGroupPolicy sets how the pushed group handles member errors, overriding the pool's default policy
+func WithGroupPolicy(policy GroupPolicy) PushOpt {
+	return func(cfg *pushConfig) {
+		cfg.groupPolicy = &policy
+	}
+}
+
+// WithPriority queues the pushed job(s) in the lane of the given priority; the default is PriorityNormal
+func WithPriority(priority Priority) PushOpt {
+	return func(cfg *pushConfig) {
+		cfg.priority = priority
+	}
+}
+
+// WithEmitter hands the final outcome of the pushed job(s) to the supplied emitter, in place of the pool's emitter or
+// results channel; this lets a pool serve several orchestrators at once
+func WithEmitter(emitter Emitter) PushOpt {
+	return func(cfg *pushConfig) {
+		cfg.emitter = emitter
+	}
+}
+
+// WithJobKey names the pushed job in the metrics of the pool, as the keys of a group do for its members; the default
+// is "job"
+func WithJobKey(key string) PushOpt {
+	return func(cfg *pushConfig) {
+		cfg.key = key
+	}
+}
+
+// WithTraceContext parents the spans of the pushed job(s) to the span carried by ctx, such as the span of the block
+// being processed; this trace context then travels with the results into downstream pools
+func WithTraceContext(ctx context.Context) PushOpt {
+	return func(cfg *pushConfig) {
+		cfg.traceCtx = trace.SpanContextFromContext(ctx)
+	}
+}
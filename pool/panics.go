@@ -0,0 +1,54 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is reported in place of a panic raised by a Runner or FeedTransformer; the worker that recovered the
+// panic stays alive. The stack is logged where the panic is recovered, and kept in Stack rather than in the message,
+// so that errors joining or wrapping a PanicError stay readable
+type PanicError struct {
+	JobID string
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("job [%s] panicked: %v", e.JobID, e.Value)
+}
+
+// run executes the Runner of a job, converting a panic into a PanicError
+func (wp *WorkerPool) run(ctx context.Context, job *job) (res interface{}, err error) {
+	defer func() {
+		if val := recover(); val != nil {
+			res, err = nil, wp.recovered(job.id, val)
+		}
+	}()
+	return job.fn(ctx)
+}
+
+// transform applies a FeedTransformer to a feed payload; if the transformer panics, the resulting Runner reports the
+// panic as a PanicError, so that the job (and its receipt) is still accounted for
+func (wp *WorkerPool) transform(transformer FeedTransformer, payload interface{}) (fn Runner) {
+	defer func() {
+		if val := recover(); val != nil {
+			err := wp.recovered("feed transformer", val)
+			fn = func(ctx context.Context) (interface{}, error) {
+				return nil, err
+			}
+		}
+	}()
+	return transformer(payload)
+}
+
+// recovered builds a PanicError from a recovered value and records it
+func (wp *WorkerPool) recovered(jobID string, val interface{}) *PanicError {
+	err := &PanicError{JobID: jobID, Value: val, Stack: debug.Stack()}
+	wp.logger.Errorf("Recovered from panic in worker pool [%s]: %v\n%s", wp.id, val, err.Stack)
+	if wp.metrics != nil {
+		wp.metrics.Incr("worker_pool.panics", []string{fmt.Sprintf("pool:%s", wp.id)}, 1.0)
+	}
+	return err
+}
@@ -3,9 +3,13 @@ package pool
 import (
 	"context"
 	"errors"
+	"fmt"
+	"github.com/coherentopensource/go-service-framework/retry"
 	"github.com/coherentopensource/go-service-framework/util"
 	"github.com/segmentio/ksuid"
+	"go.opentelemetry.io/otel/trace"
 	"sync"
+	"time"
 )
 
 const (
@@ -15,36 +19,67 @@ const (
 // WorkerPool is a configurable container for running concurrent tasks, both as one-offs and in groups
 // with a receipt signal
 type WorkerPool struct {
-	id               string
-	parentCtx        context.Context
-	countWaiting     int
-	countInProgress  int
-	waitingMu        *sync.Mutex
-	inProgressMu     *sync.Mutex
-	groups           map[string]*group
-	groupMu          *sync.Mutex
-	workerWg         *sync.WaitGroup
-	feedTransformers map[string]FeedTransformer
-	useGroupForFeed  bool
-	bandwidth        int
-	errHandler       ErrHandler
-	errCh            chan error
-	jobCh            chan job
-	resultCh         chan result
-	feedCh           <-chan result
-	cancel           context.CancelFunc
-	throttler        *Throttler
-	useOutputCh      bool
-	logger           util.Logger
+	id              string
+	parentCtx       context.Context
+	countWaiting    int
+	countInProgress int
+	countAbandoned  int
+	waitingMu       *sync.Mutex
+	inProgressMu    *sync.Mutex
+	groups          map[string]*group
+	groupMu         *sync.Mutex
+	workerWg        *sync.WaitGroup
+	bandwidth       int
+	errHandler      ErrHandler
+	errCh           chan error
+	jobCh           chan job
+	lanes           [laneCount]chan job
+	laneWeights     map[Priority]int
+	resultCh        chan result
+	feeds           map[string]*feed
+	feedMu          *sync.Mutex
+	cancel          context.CancelFunc
+	throttler       Limiter
+	useOutputCh     bool
+	logger          util.Logger
+	jobTimeout      time.Duration
+	retryPolicy     *retry.Policy
+	countRetries    int
+	countRetrying   int
+	retryMu         *sync.Mutex
+	deadLetterSink  DeadLetterSink
+	groupPolicy     GroupPolicy
+	runCtx          context.Context
+	jobWorkers      []chan struct{}
+	errWorkers      []chan struct{}
+	scaleMu         *sync.Mutex
+	autoscaler      *AutoscalerConfig
+	countPending    int
+	pendingMu       *sync.Mutex
+	draining        bool
+	countRejected   int
+	drainMu         *sync.Mutex
+	metrics         util.Metrics
+	emitter         Emitter
+	bufferSize      int
+	backpressure    Backpressure
+	backpressureMu  *sync.Mutex
+	countDropped    int
+	spill           *spill
+	stallThreshold  time.Duration
+	countSettled    int
+	stalled         bool
+	tracer          trace.Tracer
 }
 
 // NewWorkerPool instantiates a worker pool with default options
 func NewWorkerPool(id string, opts ...opt) *WorkerPool {
 	wp := WorkerPool{
-		id:        id,
-		bandwidth: defaultBandwidth,
-		// this is default anyway, but specifying for explicitness
-		useGroupForFeed: false,
+		id:          id,
+		bandwidth:   defaultBandwidth,
+		laneWeights: defaultLaneWeights,
+		feeds:       map[string]*feed{},
+		feedMu:      &sync.Mutex{},
 	}
 	wp.errHandler = wp.defaultErrHandler
 	for _, opt := range opts {
@@ -62,14 +97,27 @@ func (wp *WorkerPool) Start(parentCtx context.Context) error {
 	if wp.logger == nil {
 		return errors.New("Logger not configured")
 	}
+	if wp.backpressure.Policy == Spill && wp.backpressure.Codec == nil {
+		return errors.New("Codec not configured for Spill backpressure")
+	}
+	if wp.backpressure.Policy == DropOldest && wp.backpressure.DropWeight < 1 {
+		return errors.New("DropWeight not configured for DropOldest backpressure")
+	}
 
 	wp.parentCtx = parentCtx
 	innerCtx, cancel := context.WithCancel(parentCtx)
 	wp.cancel = cancel
 
+	wp.scaleMu.Lock()
+	wp.runCtx = innerCtx
 	wp.startJobWorkers(innerCtx)
 	wp.startErrorWorkers(innerCtx)
-	wp.startFeeder(innerCtx)
+	wp.scaleMu.Unlock()
+	wp.startDispatcher(innerCtx)
+	wp.startFeeders(innerCtx)
+	wp.startAutoscaler(innerCtx)
+	wp.startSpillDrainer(innerCtx)
+	wp.startStallDetector(innerCtx)
 
 	return nil
 }
@@ -80,76 +128,122 @@ func (wp *WorkerPool) refreshControls() {
 	wp.workerWg = &sync.WaitGroup{}
 	wp.inProgressMu = &sync.Mutex{}
 	wp.waitingMu = &sync.Mutex{}
-	wp.jobCh = make(chan job, wp.bandwidth)
+	wp.retryMu = &sync.Mutex{}
+	wp.scaleMu = &sync.Mutex{}
+	wp.pendingMu = &sync.Mutex{}
+	wp.drainMu = &sync.Mutex{}
+	wp.backpressureMu = &sync.Mutex{}
+	wp.spill = newSpill()
+	wp.jobWorkers = nil
+	wp.errWorkers = nil
+	wp.jobCh = make(chan job)
+	bufferSize := wp.bufferSize
+	if bufferSize <= 0 {
+		bufferSize = wp.bandwidth
+	}
+	for i := range wp.lanes {
+		wp.lanes[i] = make(chan job, bufferSize)
+	}
 	wp.errCh = make(chan error, wp.bandwidth)
-	wp.resultCh = make(chan result, wp.bandwidth)
+	wp.resultCh = make(chan result, bufferSize)
 }
 
-// Stop performs a graceful shutdown of all workers
+// Stop performs a graceful shutdown of all workers; jobs still queued are abandoned, releasing their receipts, as are
+// results that were never consumed, either buffered or spilled (see DropWeight)
 func (wp *WorkerPool) Stop() {
 	wp.cancel()
 	wp.workerWg.Wait()
+	wp.abandonQueued()
+	wp.abandonResults()
+	wp.closeSpill()
 	close(wp.jobCh)
 	close(wp.errCh)
 	close(wp.resultCh)
 }
 
+// FlushAndRestart stops the pool, discarding any queued work, then starts it afresh; use Drain() to flush the pool
+// without losing work
 func (wp *WorkerPool) FlushAndRestart() {
 	wp.Stop()
 	wp.refreshControls()
 	wp.Start(wp.parentCtx)
 }
 
-// SetInputFeed configures the workerpool to receive jobs from an input channel, with "transformer" methods
-// that convert a generic input interface into a Runner
-func (wp *WorkerPool) SetInputFeed(feed <-chan result, transformers ...FeedTransformer) {
-	if wp.feedCh != nil {
-		wp.logger.Warn("Attempting to set input feed, when input feed is already defined")
+// PushGroup queues a group of Runners for execution, with a receipt signal to be sent to the supplied receiptWg when
+// all Runners are completed
+func (wp *WorkerPool) PushGroup(fns map[string]Runner, wg *sync.WaitGroup, opts ...PushOpt) {
+	cfg := newPushConfig(opts)
+	if wp.reject(len(fns), wg, cfg.emitter) {
 		return
 	}
-
-	wp.feedCh = feed
-	wp.feedTransformers = map[string]FeedTransformer{}
-	for _, transformer := range transformers {
-		wp.feedTransformers[ksuid.New().String()] = transformer
-	}
+	wp.pushGroup(fns, wg, cfg)
 }
 
-// SetGroupInputFeed configures the workerpool to receive jobs from an input channel, with "transformer" methods
-// that convert a generic input interface into a Runner; with the runners executed as a group
-func (wp *WorkerPool) SetGroupInputFeed(feed <-chan result, groupMap map[string]FeedTransformer) {
-	if wp.feedCh != nil {
-		wp.logger.Warn("Attempting to set group input feed, when input feed is already defined")
-		return
+// pushGroup queues a group of Runners, regardless of whether the pool is draining
+func (wp *WorkerPool) pushGroup(fns map[string]Runner, wg *sync.WaitGroup, cfg pushConfig) {
+	policy := wp.groupPolicy
+	if cfg.groupPolicy != nil {
+		policy = *cfg.groupPolicy
 	}
-
-	wp.feedCh = feed
-	wp.feedTransformers = groupMap
-	wp.useGroupForFeed = true
-}
-
-// PushGroup queues a group of Runners for execution, with a receipt signal to be sent to the supplied receiptWg when
-// all Runners are completed
-func (wp *WorkerPool) PushGroup(fns map[string]Runner, wg *sync.WaitGroup) {
 	groupID := ksuid.New().String()
+	//	root the group at the running pool, so that stopping the pool cancels the group; groups pushed before Start()
+	//	have no pool context to root at yet
+	wp.scaleMu.Lock()
+	parentCtx := wp.runCtx
+	wp.scaleMu.Unlock()
+	if parentCtx == nil {
+		parentCtx = context.Background()
+	}
+	groupCtx, cancel := context.WithCancel(parentCtx)
 	wp.groupMu.Lock()
 	defer wp.groupMu.Unlock()
 	wp.groups[groupID] = &group{
 		results:  ResultSet{},
+		errors:   map[string]error{},
 		cursor:   0,
 		jobCount: len(fns),
+		policy:   policy,
+		ctx:      groupCtx,
+		cancel:   cancel,
 	}
+	wp.incrPending(len(fns))
 	go func() {
 		for jobID, fn := range fns {
-			wp.jobCh <- job{fn: fn, groupID: groupID, id: jobID, receiptWg: wg}
+			wp.enqueue(job{fn: fn, groupID: groupID, id: jobID, receiptWg: wg, timeout: cfg.timeout, descriptor: cfg.descriptor, priority: cfg.priority, emitter: cfg.emitter, key: jobID, traceCtx: cfg.traceCtx})
 		}
 	}()
 }
 
 // PushJob queues a one-off job for execution
-func (wp *WorkerPool) PushJob(fn Runner, wg *sync.WaitGroup) {
+func (wp *WorkerPool) PushJob(fn Runner, wg *sync.WaitGroup, opts ...PushOpt) {
+	cfg := newPushConfig(opts)
+	if wp.reject(1, wg, cfg.emitter) {
+		return
+	}
 	id := ksuid.New().String()
-	wp.jobCh <- job{fn: fn, id: id, receiptWg: wg}
+	wp.incrPending(1)
+	wp.enqueue(job{fn: fn, id: id, receiptWg: wg, timeout: cfg.timeout, descriptor: cfg.descriptor, priority: cfg.priority, emitter: cfg.emitter, key: cfg.key, traceCtx: cfg.traceCtx})
+}
+
+// enqueue queues a job in the lane matching its priority
+func (wp *WorkerPool) enqueue(job job) {
+	job.enqueuedAt = time.Now()
+	wp.laneFor(&job) <- job
+}
+
+// SetEmitter hands the final outcome of every job and group to the supplied emitter, in place of the results channel,
+// unless a different emitter is given per push with WithEmitter(); this is the integration point for orchestrators
+// (such as the pipeline package) that route results themselves
+func (wp *WorkerPool) SetEmitter(emitter Emitter) {
+	wp.emitter = emitter
+}
+
+// emitterFor resolves the emitter of a push, falling back to the pool's emitter
+func (wp *WorkerPool) emitterFor(emitter Emitter) Emitter {
+	if emitter != nil {
+		return emitter
+	}
+	return wp.emitter
 }
 
 // Results gives public access to a channel that will receive results as they are processed; requires that the
@@ -158,126 +252,257 @@ func (wp *WorkerPool) Results() <-chan result {
 	return wp.resultCh
 }
 
-// startFeeder spins up a worker to read off of a feed channel, if a feeder has been specified with the SetInputFeed()
-// method
-func (wp *WorkerPool) startFeeder(ctx context.Context) {
-	if wp.feedTransformers == nil || wp.feedCh == nil {
-		return
+// startJobWorkers spins up workers to process jobs
+func (wp *WorkerPool) startJobWorkers(ctx context.Context) {
+	for i := 0; i < wp.bandwidth; i++ {
+		wp.jobWorkers = append(wp.jobWorkers, wp.startJobWorker(ctx))
 	}
+}
 
+// startJobWorker spins up a single worker to process jobs, returning a channel that stops the worker when closed; a
+// stopped worker finishes its current job first, since jobs run under the pool context rather than the worker's
+func (wp *WorkerPool) startJobWorker(ctx context.Context) chan struct{} {
+	quitCh := make(chan struct{})
 	wp.workerWg.Add(1)
-	go func() {
+	go func(ctx context.Context) {
 		defer wp.workerWg.Done()
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case res := <-wp.feedCh:
-				group := map[string]Runner{}
-				for id, transformer := range wp.feedTransformers {
-					switch {
-					case wp.useGroupForFeed:
-						group[id] = transformer(res.payload)
-					default:
-						wp.jobCh <- job{fn: transformer(res.payload), receiptWg: res.wg, id: ksuid.New().String()}
-					}
+			case <-quitCh:
+				return
+			case job := <-wp.jobCh:
+				queueWait := time.Since(job.enqueuedAt)
+				if err := wp.throttle(ctx, &job); err != nil {
+					//	the pool is stopping, so the job is released unrun
+					wp.finish(&job, nil, err)
+					continue
 				}
-				if wp.useGroupForFeed {
-					wp.PushGroup(group, res.wg)
+				wp.incrInProgress()
+				started := time.Now()
+				spanCtx, span := wp.startSpan(ctx, &job)
+				res, err := wp.execute(spanCtx, &job)
+				wp.endSpan(span, err)
+				wp.observe(&job, queueWait, time.Since(started), err)
+				if job.attempts == 0 {
+					job.firstRun = started
 				}
+				job.attempts++
+				if delay, ok := wp.retryDelay(ctx, &job, err); ok {
+					wp.scheduleRetry(ctx, job, err, delay)
+				} else {
+					wp.finish(&job, res, err)
+				}
+				wp.decrInProgress()
 			}
 		}
-	}()
+	}(ctx)
+	return quitCh
 }
 
-// startJobWorkers spins up workers to process jobs
-func (wp *WorkerPool) startJobWorkers(ctx context.Context) {
-	for i := 0; i < wp.bandwidth; i++ {
-		wp.workerWg.Add(1)
-		go func(ctx context.Context) {
-			defer wp.workerWg.Done()
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case job := <-wp.jobCh:
-					if wp.throttler != nil {
-						wp.incrWaiting()
-						wp.throttler.WaitForGo()
-						wp.decrWaiting()
-					}
-					wp.incrInProgress()
-					switch {
-					case job.groupID != "":
-						res, err := job.fn(ctx)
-						wp.processGroupResult(&job, res, err)
-					default:
-						res, err := job.fn(ctx)
-						if err != nil {
-							wp.errCh <- err
-							job.receiptWg.Done()
-							break
-						}
-						if wp.useOutputCh {
-							wp.resultCh <- result{payload: res, wg: job.receiptWg}
-						}
-						job.receiptWg.Done()
-					}
-					wp.decrInProgress()
-				}
+// finish processes the final result (or error) of a job, releasing its receipt
+func (wp *WorkerPool) finish(job *job, res interface{}, err error) {
+	defer wp.decrPending()
+	if job.groupID != "" {
+		wp.processGroupResult(job, res, err)
+		return
+	}
+	if err != nil {
+		wp.deadLetter(job, err)
+		wp.reportErr(err)
+	}
+	wp.emit(job, res, err)
+	job.receiptWg.Done()
+}
+
+// execute runs the Runner of a job under its own context, derived from the worker context and bounded by the job's
+// timeout (or the pool's default timeout); a job that overruns its timeout releases the worker even if the Runner
+// ignores context cancellation. Such a Runner keeps running in the background until it returns: it is counted as
+// "abandoned" in Insights() until then, and its result is discarded
+func (wp *WorkerPool) execute(ctx context.Context, job *job) (interface{}, error) {
+	timeout := job.timeout
+	if timeout <= 0 {
+		timeout = wp.jobTimeout
+	}
+
+	var jobCtx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		jobCtx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		jobCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	//	members of a group are also cancelled when the group is (i.e. when a FailFast sibling fails)
+	if job.groupID != "" {
+		groupCtx := wp.groupContext(job.groupID)
+		if groupCtx == nil {
+			return nil, fmt.Errorf("job [%s] of group [%s] in worker pool [%s]: %w", job.id, job.groupID, wp.id, ErrGroupNotFound)
+		}
+		if groupCtx.Err() != nil {
+			return nil, ErrGroupCancelled
+		}
+		go func() {
+			select {
+			case <-groupCtx.Done():
+				cancel()
+			case <-jobCtx.Done():
 			}
-		}(ctx)
+		}()
+	}
+
+	if timeout <= 0 {
+		return wp.run(jobCtx, job)
+	}
+
+	type outcome struct {
+		res interface{}
+		err error
+	}
+	doneCh := make(chan outcome, 1)
+	//	a Runner still running when the job is given up on is counted as abandoned until it returns
+	stateMu := &sync.Mutex{}
+	finished, abandoned := false, false
+	go func() {
+		res, err := wp.run(jobCtx, job)
+		stateMu.Lock()
+		finished = true
+		if abandoned {
+			wp.decrAbandoned()
+		}
+		stateMu.Unlock()
+		doneCh <- outcome{res: res, err: err}
+	}()
+
+	select {
+	case out := <-doneCh:
+		if out.err != nil && errors.Is(jobCtx.Err(), context.DeadlineExceeded) {
+			return nil, newTimeoutError(job, timeout)
+		}
+		return out.res, out.err
+	case <-jobCtx.Done():
+		stateMu.Lock()
+		if !finished {
+			abandoned = true
+			wp.incrAbandoned()
+		}
+		stateMu.Unlock()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, newTimeoutError(job, timeout)
 	}
 }
 
 // startErrorWorkers spins up workers to process errors
 func (wp *WorkerPool) startErrorWorkers(ctx context.Context) {
 	for i := 0; i < wp.bandwidth; i++ {
-		wp.workerWg.Add(1)
-		go func(ctx context.Context) {
-			defer wp.workerWg.Done()
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case err := <-wp.errCh:
-					wp.errHandler(err)
-				}
-			}
-		}(ctx)
+		wp.errWorkers = append(wp.errWorkers, wp.startErrorWorker(ctx))
 	}
 }
 
+// startErrorWorker spins up a single worker to process errors, returning a channel that stops the worker when closed
+func (wp *WorkerPool) startErrorWorker(ctx context.Context) chan struct{} {
+	quitCh := make(chan struct{})
+	wp.workerWg.Add(1)
+	go func(ctx context.Context) {
+		defer wp.workerWg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-quitCh:
+				return
+			case err := <-wp.errCh:
+				wp.errHandler(err)
+			}
+		}
+	}(ctx)
+	return quitCh
+}
+
 // processGroupResult processes the result (or error) for a job, given the job is part of a group
 func (wp *WorkerPool) processGroupResult(job *job, res interface{}, err error) {
+	defer job.receiptWg.Done()
+
 	//	lock group mutex
 	wp.groupMu.Lock()
-	defer wp.groupMu.Unlock()
-	defer job.receiptWg.Done()
 
-	//	pull group from memory
-	group := wp.groups[job.groupID]
+	//	pull group from memory; members of a group the pool no longer tracks are reported on their own
+	group, ok := wp.groups[job.groupID]
+	if !ok {
+		wp.groupMu.Unlock()
+		if err == nil {
+			err = ErrGroupNotFound
+		}
+		err = fmt.Errorf("job [%s] of group [%s] in worker pool [%s]: %w", job.id, job.groupID, wp.id, err)
+		wp.reportErr(err)
+		wp.emit(job, nil, err)
+		return
+	}
 
 	//	increment cursor
 	group.cursor++
 
-	//	accumulate result
-	group.results[job.id] = res
+	//	accumulate result or error; members cancelled by a failed FailFast group are not errors in their own right
+	switch {
+	case err == nil:
+		group.results[job.id] = res
+	case group.policy == FailFast && group.ctx.Err() != nil:
+	default:
+		wp.deadLetter(job, err)
+		group.errors[job.id] = err
+		if group.policy == FailFast {
+			group.cancel()
+		}
+	}
+
+	//	unless we're complete with the group, there is nothing to emit
+	complete := group.cursor == group.jobCount
+	if complete {
+		group.cancel()
+		delete(wp.groups, job.groupID)
+	}
+	wp.groupMu.Unlock()
+	if !complete {
+		return
+	}
 
-	//	if we're complete with the group, send wg receipt and push result to results ch, if applicable
-	if group.cursor == group.jobCount {
-		//	if this is an error, push the error and exit
-		if err != nil {
-			wp.errCh <- err
-			delete(wp.groups, job.groupID)
+	//	report the failed members as a single error, and forward what we have only if the policy allows it
+	if len(group.errors) > 0 {
+		groupErr := &GroupError{GroupID: job.groupID, Errors: group.errors}
+		wp.reportErr(groupErr)
+		if group.policy != BestEffort {
+			wp.emit(job, nil, groupErr)
 			return
 		}
+		wp.emit(job, PartialResultSet{Results: group.results, Errors: group.errors}, nil)
+		return
+	}
+	wp.emit(job, ResultSet(group.results), nil)
+}
 
-		if wp.useOutputCh {
-			wp.resultCh <- result{payload: ResultSet(group.results), wg: job.receiptWg}
-		}
+// emit hands the final outcome of a job or group to its emitter (or the pool's), if one is set; otherwise successful
+// results are pushed to the results channel, if applicable
+func (wp *WorkerPool) emit(job *job, payload interface{}, err error) {
+	if emitter := wp.emitterFor(job.emitter); emitter != nil {
+		emitter(Emission{Payload: payload, Err: err, Receipt: job.receiptWg, TraceContext: job.traceCtx})
+		return
+	}
+	if err == nil && wp.useOutputCh {
+		wp.publish(result{payload: payload, wg: job.receiptWg, priority: job.priority, traceCtx: job.traceCtx})
+	}
+}
 
-		delete(wp.groups, job.groupID)
+// reportErr queues an error for the error workers, handling it inline if the queue is full so that a worker never
+// blocks on error reporting (e.g. while the pool is stopping)
+func (wp *WorkerPool) reportErr(err error) {
+	select {
+	case wp.errCh <- err:
+	default:
+		wp.errHandler(err)
 	}
 }
 
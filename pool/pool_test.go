@@ -2,9 +2,17 @@ package pool_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/coherentopensource/go-service-framework/cache"
 	"github.com/coherentopensource/go-service-framework/pool"
+	"github.com/coherentopensource/go-service-framework/retry"
+	"github.com/coherentopensource/go-service-framework/tracing"
+	"github.com/coherentopensource/go-service-framework/util"
 	"go.uber.org/zap"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -220,3 +228,1376 @@ func TestGroupFeed(t *testing.T) {
 		return
 	}
 }
+
+func TestTypedStages(t *testing.T) {
+	//	Instantiate logger
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+
+	//	Instantiate the stages: one-off ints -> group of strings -> joined result
+	entry := pool.NewStage[struct{}, int]("entry", pool.WithLogger(logger), pool.WithOutputChannel())
+	fetch := pool.NewGroupStage[int, string]("fetch", pool.WithLogger(logger), pool.WithOutputChannel())
+	sum := pool.NewStage[pool.TypedResultSet[string], int]("sum", pool.WithLogger(logger))
+
+	fetch.SetGroupInputFeed(entry.Results(), map[string]pool.TypedTransformer[int, string]{
+		"double": func(in int) pool.TypedRunner[string] {
+			return func(ctx context.Context) (string, error) {
+				return fmt.Sprintf("%d", in*2), nil
+			}
+		},
+		"triple": func(in int) pool.TypedRunner[string] {
+			return func(ctx context.Context) (string, error) {
+				return fmt.Sprintf("%d", in*3), nil
+			}
+		},
+	})
+
+	//	Capture the accumulated output of the final stage
+	var got string
+	sum.SetInputFeed(fetch.Results(), func(in pool.TypedResultSet[string]) pool.TypedRunner[int] {
+		return func(ctx context.Context) (int, error) {
+			got = in["double"] + "," + in["triple"]
+			return len(in), nil
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	entry.Start(ctx)
+	fetch.Start(ctx)
+	sum.Start(ctx)
+	defer entry.Stop()
+	defer fetch.Stop()
+	defer sum.Stop()
+
+	//	One entry job, two group jobs and one summing job
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+	entry.PushJob(func(ctx context.Context) (int, error) {
+		return 7, nil
+	}, wg)
+
+	endCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(endCh)
+	}()
+	select {
+	case <-endCh:
+	case <-ctx.Done():
+		t.Fatal("Test timed out")
+	}
+
+	if got != "14,21" {
+		t.Errorf("Expected typed results [14,21], but got [%s]", got)
+	}
+
+	//	Payloads of a bridged legacy pool that don't match the feed's type fail their jobs instead of running them
+	legacy := pool.NewWorkerPool("legacy", pool.WithLogger(logger), pool.WithOutputChannel())
+	errs := make(chan error, 1)
+	typed := pool.NewStage[int, int]("typed", pool.WithLogger(logger), pool.WithErrHandler(func(err error) {
+		errs <- err
+	}))
+	ran := false
+	typed.SetInputFeed(pool.FeedOf[int](legacy), func(in int) pool.TypedRunner[int] {
+		return func(ctx context.Context) (int, error) {
+			ran = true
+			return in, nil
+		}
+	})
+	legacy.Start(ctx)
+	typed.Start(ctx)
+	defer legacy.Stop()
+	defer typed.Stop()
+
+	wg.Add(2)
+	legacy.PushJob(func(ctx context.Context) (interface{}, error) {
+		return "not an int", nil
+	}, wg)
+	select {
+	case err := <-errs:
+		if !errors.Is(err, pool.ErrTypeMismatch) {
+			t.Errorf("Expected a type mismatch error, got: %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("Type mismatch was not reported")
+	}
+	wg.Wait()
+	if ran {
+		t.Error("Expected the mismatched payload not to be run")
+	}
+}
+
+func TestJobTimeout(t *testing.T) {
+	//	Instantiate logger
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+
+	//	Capture errors routed to the error handler
+	errs := make(chan error, 2)
+	wp := pool.NewWorkerPool(
+		"timeouts",
+		pool.WithLogger(logger),
+		pool.WithBandwidth(1),
+		pool.WithDefaultJobTimeout(5*time.Second),
+		pool.WithErrHandler(func(err error) {
+			errs <- err
+		}),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	wp.Start(ctx)
+	defer wp.Stop()
+
+	//	A job that ignores its context entirely, followed by one that should still get a worker
+	hang := make(chan struct{})
+	defer close(hang)
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	wp.PushJob(func(ctx context.Context) (interface{}, error) {
+		<-hang
+		return nil, nil
+	}, wg, pool.WithJobTimeout(100*time.Millisecond))
+	wp.PushJob(func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	}, wg)
+
+	endCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(endCh)
+	}()
+	select {
+	case <-endCh:
+	case <-ctx.Done():
+		t.Fatal("Test timed out; hung job was not released")
+	}
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, pool.ErrJobTimeout) {
+			t.Errorf("Expected a timeout error, but got %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("Timeout error was not routed to the error handler")
+	}
+
+	//	The hung runner is still running, and is reported as abandoned until it returns
+	if abandoned := wp.Insights()["abandoned"]; abandoned != 1 {
+		t.Errorf("Expected 1 abandoned runner, but got %d", abandoned)
+	}
+	hang <- struct{}{}
+	for wp.Insights()["abandoned"] != 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("Abandoned runner was never released")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestJobRetry(t *testing.T) {
+	//	Instantiate logger
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+
+	//	Retry quickly, and never retry errors flagged as permanent
+	errPermanent := errors.New("permanent")
+	policy := retry.Constant(10 * time.Millisecond)
+	policy.MaxAttempts = 3
+	wp := pool.NewWorkerPool("retries", pool.WithLogger(logger), pool.WithOutputChannel(), pool.WithJobRetry(policy))
+
+	//	Capture the group result downstream
+	var groupSize int
+	sink := pool.NewWorkerPool("sink", pool.WithLogger(logger))
+	sink.SetInputFeed(wp.Results(), func(res interface{}) pool.Runner {
+		return func(ctx context.Context) (interface{}, error) {
+			groupSize = len(res.(pool.ResultSet))
+			return nil, nil
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	wp.Start(ctx)
+	sink.Start(ctx)
+	defer wp.Stop()
+	defer sink.Stop()
+
+	//	A flaky group member that succeeds on its last allowed attempt, and one that fails permanently
+	flakyAttempts := 0
+	permanentAttempts := 0
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+	wp.PushGroup(map[string]pool.Runner{
+		"flaky": func(ctx context.Context) (interface{}, error) {
+			flakyAttempts++
+			if flakyAttempts < policy.MaxAttempts {
+				return nil, errors.New("transient")
+			}
+			return "ok", nil
+		},
+		"steady": func(ctx context.Context) (interface{}, error) {
+			return "ok", nil
+		},
+	}, wg)
+	wp.PushJob(func(ctx context.Context) (interface{}, error) {
+		permanentAttempts++
+		return nil, retry.Permanent(errPermanent)
+	}, wg)
+
+	endCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(endCh)
+	}()
+	select {
+	case <-endCh:
+	case <-ctx.Done():
+		t.Fatal("Test timed out")
+	}
+
+	//	The group should have been forwarded intact once the flaky member succeeded
+	if groupSize != 2 {
+		t.Errorf("Expected a result set of 2 results, but got %d", groupSize)
+	}
+	if flakyAttempts != policy.MaxAttempts {
+		t.Errorf("Expected %d attempts of the flaky job, but got %d", policy.MaxAttempts, flakyAttempts)
+	}
+	if permanentAttempts != 1 {
+		t.Errorf("Expected 1 attempt of the permanently failing job, but got %d", permanentAttempts)
+	}
+	if retries := wp.Insights()["retries"]; retries != policy.MaxAttempts-1 {
+		t.Errorf("Expected %d retries in insights, but got %d", policy.MaxAttempts-1, retries)
+	}
+
+	//	A policy bounded neither in attempts nor in time is limited to 3 attempts, and MaxElapsed bounds retries in time
+	for name, policy := range map[string]retry.Policy{
+		"unbounded":   retry.Constant(time.Millisecond),
+		"max elapsed": {Backoff: retry.Constant(40 * time.Millisecond).Backoff, MaxElapsed: 100 * time.Millisecond},
+	} {
+		t.Run(name, func(t *testing.T) {
+			bounded := pool.NewWorkerPool("bounded", pool.WithLogger(logger), pool.WithJobRetry(policy))
+			bounded.Start(ctx)
+			defer bounded.Stop()
+
+			attempts := 0
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+			bounded.PushJob(func(ctx context.Context) (interface{}, error) {
+				attempts++
+				return nil, errors.New("unavailable")
+			}, wg)
+			wg.Wait()
+			if attempts != 3 {
+				t.Errorf("Expected 3 attempts, but got %d", attempts)
+			}
+		})
+	}
+}
+
+func TestDeadLetterReplay(t *testing.T) {
+	//	Instantiate logger
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+
+	sinks := map[string]pool.DeadLetterSink{
+		"memory": pool.NewMemoryDeadLetterSink(),
+		"file":   pool.NewFileDeadLetterSink(t.TempDir() + "/dead_letters.jsonl"),
+		"redis":  pool.NewRedisDeadLetterSink(newFakeListStore(), "dead-letters"),
+	}
+	for name, sink := range sinks {
+		t.Run(name, func(t *testing.T) {
+			wp := pool.NewWorkerPool("dead-letters", pool.WithLogger(logger), pool.WithDeadLetterSink(sink))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			wp.Start(ctx)
+			defer wp.Stop()
+
+			//	Fail a job that describes the block it was working on
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+			wp.PushJob(func(ctx context.Context) (interface{}, error) {
+				return nil, errors.New("node unavailable")
+			}, wg, pool.WithDescriptor("1234"))
+			wg.Wait()
+
+			//	Replay it with a resolver that rebuilds a working job from the descriptor
+			replayedBlock := ""
+			replayed, err := wp.Replay(ctx, sink, func(entry *pool.DeadLetter) (pool.Runner, error) {
+				if entry.PoolID != "dead-letters" || entry.Attempts != 1 || entry.Error != "node unavailable" {
+					t.Errorf("Unexpected dead letter entry: %+v", entry)
+				}
+				wg.Add(1)
+				return func(ctx context.Context) (interface{}, error) {
+					replayedBlock = entry.Descriptor
+					return nil, nil
+				}, nil
+			}, wg)
+			if err != nil {
+				t.Fatalf("Error replaying dead letters: %v", err)
+			}
+			wg.Wait()
+
+			if replayed != 1 || replayedBlock != "1234" {
+				t.Errorf("Expected block 1234 to be replayed once, but got %d replays of [%s]", replayed, replayedBlock)
+			}
+			if entries, _ := sink.Drain(ctx); len(entries) != 0 {
+				t.Errorf("Expected the sink to be empty after replay, but found %d entries", len(entries))
+			}
+		})
+	}
+}
+
+// fakeListStore holds Redis lists in memory; popping fails once failAfter entries have been popped, if set
+type fakeListStore struct {
+	mu        *sync.Mutex
+	lists     map[string][]string
+	pops      int
+	failAfter int
+}
+
+func newFakeListStore() *fakeListStore {
+	return &fakeListStore{mu: &sync.Mutex{}, lists: map[string][]string{}}
+}
+
+func (s *fakeListStore) RPush(ctx context.Context, key string, values ...interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, value := range values {
+		s.lists[key] = append(s.lists[key], fmt.Sprint(value))
+	}
+	return nil
+}
+
+func (s *fakeListStore) LPop(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failAfter > 0 && s.pops >= s.failAfter {
+		return "", errors.New("connection reset")
+	}
+	if len(s.lists[key]) == 0 {
+		return "", &cache.NotInRedisCacheError{}
+	}
+	line := s.lists[key][0]
+	s.lists[key] = s.lists[key][1:]
+	s.pops++
+	return line, nil
+}
+
+func TestRedisDeadLetterSink(t *testing.T) {
+	//	Instantiate logger
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("undecodable entries", func(t *testing.T) {
+		//	An undecodable entry is kept in the list, without holding back the entries around it
+		store := newFakeListStore()
+		sink := pool.NewRedisDeadLetterSink(store, "dead-letters")
+		sink.Put(ctx, &pool.DeadLetter{JobID: "a"})
+		store.RPush(ctx, "dead-letters", "{not json")
+		sink.Put(ctx, &pool.DeadLetter{JobID: "b"})
+
+		entries, err := sink.Drain(ctx)
+		if err == nil {
+			t.Error("Expected the undecodable entry to be reported")
+		}
+		if len(entries) != 2 || entries[0].JobID != "a" || entries[1].JobID != "b" {
+			t.Errorf("Expected entries a and b to be drained, but got %+v", entries)
+		}
+		if remaining := store.lists["dead-letters"]; fmt.Sprint(remaining) != "[{not json]" {
+			t.Errorf("Expected only the undecodable entry to remain, but got %v", remaining)
+		}
+	})
+
+	t.Run("replay after a failed pop", func(t *testing.T) {
+		//	Entries popped before Redis failed are still replayed, and the rest stay in the list
+		store := newFakeListStore()
+		store.failAfter = 2
+		sink := pool.NewRedisDeadLetterSink(store, "dead-letters")
+		for _, id := range []string{"a", "b", "c"} {
+			sink.Put(ctx, &pool.DeadLetter{JobID: id, Descriptor: id})
+		}
+
+		wp := pool.NewWorkerPool("dead-letters", pool.WithLogger(logger))
+		wp.Start(ctx)
+		defer wp.Stop()
+
+		wg := &sync.WaitGroup{}
+		replayedMu := &sync.Mutex{}
+		var replayedIDs []string
+		replayed, err := wp.Replay(ctx, sink, func(entry *pool.DeadLetter) (pool.Runner, error) {
+			wg.Add(1)
+			return func(ctx context.Context) (interface{}, error) {
+				replayedMu.Lock()
+				replayedIDs = append(replayedIDs, entry.Descriptor)
+				replayedMu.Unlock()
+				return nil, nil
+			}, nil
+		}, wg)
+		wg.Wait()
+
+		if err == nil {
+			t.Error("Expected the failed pop to be reported")
+		}
+		sort.Strings(replayedIDs)
+		if replayed != 2 || fmt.Sprint(replayedIDs) != "[a b]" {
+			t.Errorf("Expected a and b to be replayed, but got %d replays of %v", replayed, replayedIDs)
+		}
+		if remaining := store.lists["dead-letters"]; len(remaining) != 1 {
+			t.Errorf("Expected c to remain in the list, but got %v", remaining)
+		}
+	})
+}
+
+func TestGroupPolicies(t *testing.T) {
+	//	Instantiate logger
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+
+	errFailed := errors.New("failed")
+	members := func() map[string]pool.Runner {
+		return map[string]pool.Runner{
+			"fails": func(ctx context.Context) (interface{}, error) {
+				return nil, errFailed
+			},
+			"slow": func(ctx context.Context) (interface{}, error) {
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(time.Second):
+					return "slow", nil
+				}
+			},
+		}
+	}
+
+	cases := map[string]struct {
+		policy          pool.GroupPolicy
+		expectForwarded bool
+		expectErrors    int
+		expectMaxTime   time.Duration
+	}{
+		"fail fast":   {policy: pool.FailFast, expectForwarded: false, expectErrors: 1, expectMaxTime: 500 * time.Millisecond},
+		"collect all": {policy: pool.CollectAll, expectForwarded: false, expectErrors: 1, expectMaxTime: 5 * time.Second},
+		"best effort": {policy: pool.BestEffort, expectForwarded: true, expectErrors: 1, expectMaxTime: 5 * time.Second},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			groupErrs := make(chan *pool.GroupError, 1)
+			wp := pool.NewWorkerPool("groups", pool.WithLogger(logger), pool.WithOutputChannel(), pool.WithErrHandler(func(err error) {
+				var groupErr *pool.GroupError
+				if errors.As(err, &groupErr) {
+					groupErrs <- groupErr
+				}
+			}))
+
+			//	Capture anything forwarded downstream
+			forwarded := false
+			sink := pool.NewWorkerPool("sink", pool.WithLogger(logger))
+			sink.SetInputFeed(wp.Results(), func(res interface{}) pool.Runner {
+				return func(ctx context.Context) (interface{}, error) {
+					partial := res.(pool.PartialResultSet)
+					forwarded = partial.Results["slow"] == "slow" && errors.Is(partial.Errors["fails"], errFailed)
+					return nil, nil
+				}
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			wp.Start(ctx)
+			sink.Start(ctx)
+			defer wp.Stop()
+			defer sink.Stop()
+
+			wg := &sync.WaitGroup{}
+			wg.Add(2)
+			if tc.expectForwarded {
+				wg.Add(1)
+			}
+			start := time.Now()
+			wp.PushGroup(members(), wg, pool.WithGroupPolicy(tc.policy))
+			wg.Wait()
+
+			if elapsed := time.Since(start); elapsed > tc.expectMaxTime {
+				t.Errorf("Expected group to settle within %s, but it took %s", tc.expectMaxTime, elapsed)
+			}
+			if forwarded != tc.expectForwarded {
+				t.Errorf("Expected forwarded to be %t, but got %t", tc.expectForwarded, forwarded)
+			}
+			select {
+			case groupErr := <-groupErrs:
+				if len(groupErr.Errors) != tc.expectErrors || !errors.Is(groupErr, errFailed) {
+					t.Errorf("Expected %d member error(s) wrapping the failure, but got %v", tc.expectErrors, groupErr)
+				}
+			case <-ctx.Done():
+				t.Fatal("Group error was not reported")
+			}
+		})
+	}
+}
+
+func TestSetBandwidth(t *testing.T) {
+	//	Instantiate logger
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+
+	wp := pool.NewWorkerPool("scaling", pool.WithLogger(logger), pool.WithBandwidth(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	wp.Start(ctx)
+	defer wp.Stop()
+
+	//	Queue up more slow jobs than a single worker can get through promptly
+	jobCount := 8
+	wg := &sync.WaitGroup{}
+	wg.Add(jobCount)
+	go func() {
+		for i := 0; i < jobCount; i++ {
+			wp.PushJob(func(ctx context.Context) (interface{}, error) {
+				time.Sleep(200 * time.Millisecond)
+				return nil, nil
+			}, wg)
+		}
+	}()
+
+	//	Scale up mid-flight; every queued job should still run, in roughly one job's time rather than eight
+	start := time.Now()
+	wp.SetBandwidth(jobCount)
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed > time.Duration(jobCount-2)*200*time.Millisecond {
+		t.Errorf("Expected scaled-up pool to finish quickly, but it took %s", elapsed)
+	}
+
+	//	Scale back down, then make sure the remaining worker still processes jobs
+	wp.SetBandwidth(1)
+	if bandwidth := wp.Insights()["bandwidth"]; bandwidth != 1 {
+		t.Errorf("Expected bandwidth of 1, but got %d", bandwidth)
+	}
+	wg.Add(1)
+	wp.PushJob(func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	}, wg)
+	wg.Wait()
+}
+
+func TestPriorityLanes(t *testing.T) {
+	//	Instantiate logger
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+
+	//	Lanes are sized by the initial bandwidth; drop to a single worker so that dispatch order is execution order
+	wp := pool.NewWorkerPool("lanes", pool.WithLogger(logger), pool.WithBandwidth(4), pool.WithLaneWeights(map[pool.Priority]int{
+		pool.PriorityHigh: 10,
+		pool.PriorityLow:  1,
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	wp.Start(ctx)
+	defer wp.Stop()
+	wp.SetBandwidth(1)
+
+	order := []string{}
+	orderMu := &sync.Mutex{}
+	getRunner := func(name string) pool.Runner {
+		return func(ctx context.Context) (interface{}, error) {
+			orderMu.Lock()
+			order = append(order, name)
+			orderMu.Unlock()
+			return nil, nil
+		}
+	}
+	waitFor := func(key string, val int) {
+		for wp.Insights()[key] != val {
+			select {
+			case <-ctx.Done():
+				t.Fatalf("Timed out waiting for %s to reach %d", key, val)
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}
+
+	//	Occupy the only worker, then queue backfill work; the dispatcher holds the first job while it waits for the worker
+	gate := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(9)
+	wp.PushJob(func(ctx context.Context) (interface{}, error) {
+		<-gate
+		return nil, nil
+	}, wg)
+	waitFor("inProgress", 1)
+	for i := 0; i < 4; i++ {
+		wp.PushJob(getRunner("low"), wg, pool.WithPriority(pool.PriorityLow))
+	}
+	waitFor("lane:low", 3)
+
+	//	Chaintip work arrives late, but its weight should let it jump ahead of all of the queued backfill work
+	for i := 0; i < 4; i++ {
+		wp.PushJob(getRunner("high"), wg, pool.WithPriority(pool.PriorityHigh))
+	}
+	if depth := wp.Insights()["lane:high"]; depth != 4 {
+		t.Errorf("Expected 4 jobs in the high lane, but got %d", depth)
+	}
+	close(gate)
+	wg.Wait()
+
+	expected := []string{"low", "high", "high", "high", "high", "low", "low", "low"}
+	if fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("Expected execution order %v, but got %v", expected, order)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	//	Instantiate logger
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+
+	pool1 := pool.NewWorkerPool("pool1", pool.WithLogger(logger), pool.WithOutputChannel(), pool.WithBandwidth(2))
+	pool2 := pool.NewWorkerPool("pool2", pool.WithLogger(logger), pool.WithBandwidth(2))
+
+	completedJobs := 0
+	completedJobsMutex := &sync.Mutex{}
+	getRunner := func(delay time.Duration) pool.Runner {
+		return func(ctx context.Context) (interface{}, error) {
+			time.Sleep(delay)
+			completedJobsMutex.Lock()
+			completedJobs++
+			completedJobsMutex.Unlock()
+			return nil, nil
+		}
+	}
+	pool2.SetInputFeed(pool1.Results(), func(res interface{}) pool.Runner {
+		return getRunner(50 * time.Millisecond)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	pool1.Start(ctx)
+	pool2.Start(ctx)
+	defer pool1.Stop()
+	defer pool2.Stop()
+
+	//	Drain both pools, upstream first, with plenty of time; every job and its downstream job should complete
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+	pool1.PushJob(getRunner(50*time.Millisecond), wg)
+	pool1.PushJob(getRunner(50*time.Millisecond), wg)
+	for _, wp := range []*pool.WorkerPool{pool1, pool2} {
+		if report := wp.Drain(ctx); !report.Complete() {
+			t.Errorf("Expected drain to complete, but got %+v", report)
+		}
+	}
+	completedJobsMutex.Lock()
+	if completedJobs != 4 {
+		t.Errorf("Expected 4 jobs to complete during drain, but got %d", completedJobs)
+	}
+	completedJobsMutex.Unlock()
+	wg.Wait()
+
+	//	Drain with a deadline that is too short; queued jobs are abandoned and every receipt is released
+	wg.Add(6)
+	for i := 0; i < 6; i++ {
+		pool2.PushJob(getRunner(200*time.Millisecond), wg, pool.WithDescriptor(fmt.Sprintf("%d", i)))
+	}
+	drainCtx, drainCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer drainCancel()
+	report := pool2.Drain(drainCtx)
+	if report.Complete() || len(report.Abandoned) == 0 || report.InFlight == 0 {
+		t.Errorf("Expected drain to abandon queued jobs and leave running ones in flight, but got %+v", report)
+	}
+	wg.Wait()
+
+	//	The pool accepts work again once drained
+	wg.Add(1)
+	pool2.PushJob(getRunner(0), wg)
+	wg.Wait()
+}
+
+func TestPanicRecovery(t *testing.T) {
+	//	Instantiate logger
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+
+	//	Capture panics routed to the error handler
+	panics := make(chan *pool.PanicError, 2)
+	errHandler := pool.WithErrHandler(func(err error) {
+		var panicErr *pool.PanicError
+		if errors.As(err, &panicErr) {
+			panics <- panicErr
+		}
+	})
+	pool1 := pool.NewWorkerPool("pool1", pool.WithLogger(logger), pool.WithOutputChannel(), pool.WithBandwidth(1), errHandler)
+	pool2 := pool.NewWorkerPool("pool2", pool.WithLogger(logger), pool.WithBandwidth(1), errHandler)
+	pool2.SetInputFeed(pool1.Results(), func(res interface{}) pool.Runner {
+		panic("bad transformer")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	pool1.Start(ctx)
+	pool2.Start(ctx)
+	defer pool1.Stop()
+	defer pool2.Stop()
+
+	//	A panicking runner, then a healthy runner whose result hits the panicking transformer downstream
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+	pool1.PushJob(func(ctx context.Context) (interface{}, error) {
+		panic("bad runner")
+	}, wg)
+	pool1.PushJob(func(ctx context.Context) (interface{}, error) {
+		return "ok", nil
+	}, wg)
+
+	endCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(endCh)
+	}()
+	select {
+	case <-endCh:
+	case <-ctx.Done():
+		t.Fatal("Test timed out; receipts were not released after panics")
+	}
+
+	recovered := map[interface{}]bool{}
+	for len(recovered) < 2 {
+		select {
+		case panicErr := <-panics:
+			if len(panicErr.Stack) == 0 {
+				t.Errorf("Expected a stack trace for panic [%v]", panicErr.Value)
+			}
+			if strings.Contains(panicErr.Error(), "\n") {
+				t.Errorf("Expected a single line error message, got: %s", panicErr.Error())
+			}
+			recovered[panicErr.Value] = true
+		case <-ctx.Done():
+			t.Fatalf("Panics were not routed to the error handler; got %v", recovered)
+		}
+	}
+	if !recovered["bad runner"] || !recovered["bad transformer"] {
+		t.Errorf("Expected panics from both the runner and the transformer, but got %v", recovered)
+	}
+}
+
+func TestBackpressure(t *testing.T) {
+	//	Instantiate logger
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	getRunner := func(val int) pool.Runner {
+		return func(ctx context.Context) (interface{}, error) {
+			return val, nil
+		}
+	}
+
+	//	Collect the results of a pool, in order, with a single-worker sink pool
+	var collected []interface{}
+	collectedMutex := &sync.Mutex{}
+	newSink := func(wp *pool.WorkerPool) *pool.WorkerPool {
+		collected = nil
+		sink := pool.NewWorkerPool("sink", pool.WithLogger(logger), pool.WithBandwidth(1))
+		sink.SetInputFeed(wp.Results(), func(res interface{}) pool.Runner {
+			return func(ctx context.Context) (interface{}, error) {
+				collectedMutex.Lock()
+				collected = append(collected, res)
+				collectedMutex.Unlock()
+				return nil, nil
+			}
+		})
+		return sink
+	}
+
+	t.Run("drop oldest", func(t *testing.T) {
+		//	DropOldest can't release the downstream receipts of a dropped result without knowing their number
+		wp := pool.NewWorkerPool("unweighted", pool.WithLogger(logger), pool.WithOutputChannel(),
+			pool.WithBackpressure(pool.Backpressure{Policy: pool.DropOldest}))
+		if err := wp.Start(ctx); err == nil {
+			wp.Stop()
+			t.Fatal("Expected DropOldest without a DropWeight to be rejected")
+		}
+
+		//	Nobody consumes the results, so all but the last two are dropped instead of blocking the workers; each
+		//	result stands for a single sink job, added to the receipt up front
+		wp = pool.NewWorkerPool("drop", pool.WithLogger(logger), pool.WithOutputChannel(), pool.WithBandwidth(1),
+			pool.WithBufferSize(2), pool.WithBackpressure(pool.Backpressure{Policy: pool.DropOldest, DropWeight: 1}),
+			pool.WithErrHandler(func(err error) {}))
+		wp.Start(ctx)
+		defer wp.Stop()
+
+		wg := &sync.WaitGroup{}
+		wg.Add(10)
+		for i := 0; i < 5; i++ {
+			wp.PushJob(getRunner(i), wg)
+		}
+		for wp.Insights()["dropped"] != 3 {
+			select {
+			case <-ctx.Done():
+				t.Fatalf("Expected 3 results to be dropped, but got %d", wp.Insights()["dropped"])
+			case <-time.After(time.Millisecond):
+			}
+		}
+
+		//	The receipt completes once the sink has consumed the two results that were kept
+		sink := newSink(wp)
+		sink.Start(ctx)
+		defer sink.Stop()
+		wg.Wait()
+		if fmt.Sprint(collected) != "[3 4]" {
+			t.Errorf("Expected the last two results to be kept, but got %v", collected)
+		}
+	})
+
+	t.Run("spill", func(t *testing.T) {
+		//	Results overflow to disk while nobody consumes them, then arrive in order once a consumer catches up
+		wp := pool.NewWorkerPool("spill", pool.WithLogger(logger), pool.WithOutputChannel(), pool.WithBandwidth(1),
+			pool.WithBufferSize(1), pool.WithBackpressure(pool.Backpressure{Policy: pool.Spill, Codec: pool.JSONCodec[int]{}, SpillDir: t.TempDir()}))
+		wp.Start(ctx)
+		defer wp.Stop()
+
+		wg := &sync.WaitGroup{}
+		wg.Add(10)
+		for i := 0; i < 10; i++ {
+			wp.PushJob(getRunner(i), wg)
+		}
+		wg.Wait()
+
+		if spilled := wp.Insights()["spilled"]; spilled == 0 {
+			t.Error("Expected results to be spilled")
+		}
+		sink := newSink(wp)
+		wg.Add(10)
+		sink.Start(ctx)
+		defer sink.Stop()
+		wg.Wait()
+		if fmt.Sprint(collected) != "[0 1 2 3 4 5 6 7 8 9]" {
+			t.Errorf("Expected every result in order, but got %v", collected)
+		}
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		//	Results left unconsumed when the pool stops, buffered or spilled, release the receipts of their sink jobs
+		wp := pool.NewWorkerPool("stop", pool.WithLogger(logger), pool.WithOutputChannel(), pool.WithBandwidth(1),
+			pool.WithBufferSize(1), pool.WithErrHandler(func(err error) {}),
+			pool.WithBackpressure(pool.Backpressure{Policy: pool.Spill, DropWeight: 1, Codec: pool.JSONCodec[int]{}, SpillDir: t.TempDir()}))
+		wp.Start(ctx)
+
+		wg := &sync.WaitGroup{}
+		wg.Add(10)
+		for i := 0; i < 5; i++ {
+			wp.PushJob(getRunner(i), wg)
+		}
+		for wp.Insights()["spilled"] != 4 {
+			select {
+			case <-ctx.Done():
+				t.Fatalf("Expected 4 results to be spilled, but got %d", wp.Insights()["spilled"])
+			case <-time.After(time.Millisecond):
+			}
+		}
+		dropped := wp.Insights()["dropped"]
+		wp.Stop()
+
+		endCh := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(endCh)
+		}()
+		select {
+		case <-endCh:
+		case <-ctx.Done():
+			t.Fatal("Receipt was never released for the unconsumed results")
+		}
+		if dropped != 0 {
+			t.Errorf("Expected no results to be dropped before the pool stopped, but got %d", dropped)
+		}
+	})
+
+	t.Run("stop without a reader", func(t *testing.T) {
+		//	Workers blocked on a full results channel that nobody reads give up their results once the pool stops
+		wp := pool.NewWorkerPool("unread", pool.WithLogger(logger), pool.WithOutputChannel(), pool.WithBandwidth(2),
+			pool.WithBufferSize(1), pool.WithErrHandler(func(err error) {}))
+		wp.Start(ctx)
+
+		wg := &sync.WaitGroup{}
+		wg.Add(3)
+		for i := 0; i < 3; i++ {
+			wp.PushJob(getRunner(i), wg)
+		}
+		for insights := wp.Insights(); insights["resultCh"] != 1 || insights["inProgress"] != 2; insights = wp.Insights() {
+			select {
+			case <-ctx.Done():
+				t.Fatal("Expected 2 workers to block on the results channel")
+			case <-time.After(time.Millisecond):
+			}
+		}
+
+		stopped := make(chan struct{})
+		go func() {
+			wp.Stop()
+			wg.Wait()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("Stop never returned with workers blocked on the results channel")
+		}
+		if dropped := wp.Insights()["dropped"]; dropped != 3 {
+			t.Errorf("Expected 3 unconsumed results to be dropped, but got %d", dropped)
+		}
+	})
+
+	t.Run("stall detector", func(t *testing.T) {
+		//	Blocked on a full results channel, the pool stops making progress and is reported as stalled
+		wp := pool.NewWorkerPool("stall", pool.WithLogger(logger), pool.WithOutputChannel(), pool.WithBandwidth(1),
+			pool.WithBufferSize(1), pool.WithStallDetector(50*time.Millisecond))
+		wp.Start(ctx)
+
+		wg := &sync.WaitGroup{}
+		wg.Add(3)
+		for i := 0; i < 3; i++ {
+			wp.PushJob(getRunner(i), wg)
+		}
+		time.Sleep(200 * time.Millisecond)
+		if stalled := wp.Insights()["stalled"]; stalled != 1 {
+			t.Error("Expected the pool to be reported as stalled")
+		}
+
+		//	Consuming the results lets the pool make progress again
+		sink := newSink(wp)
+		wg.Add(3)
+		sink.Start(ctx)
+		wg.Wait()
+		time.Sleep(100 * time.Millisecond)
+		if stalled := wp.Insights()["stalled"]; stalled != 0 {
+			t.Error("Expected the pool to recover from its stall")
+		}
+
+		//	Once the consumer is gone, the pool stalls again, and still stops
+		sink.Stop()
+		wg.Add(3)
+		for i := 0; i < 3; i++ {
+			wp.PushJob(getRunner(i), wg)
+		}
+		for wp.Insights()["stalled"] != 1 {
+			select {
+			case <-ctx.Done():
+				t.Fatal("Expected the pool to be reported as stalled again")
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		stopped := make(chan struct{})
+		go func() {
+			wp.Stop()
+			wg.Wait()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("Stop never returned while the pool was stalled")
+		}
+	})
+}
+
+func TestMultipleFeeds(t *testing.T) {
+	//	Instantiate logger
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+
+	//	Two upstream pools feed the same downstream pool, one as one-off jobs and the other as groups
+	blockPool := pool.NewWorkerPool("blocks", pool.WithLogger(logger), pool.WithOutputChannel())
+	contractPool := pool.NewWorkerPool("contracts", pool.WithLogger(logger), pool.WithOutputChannel())
+	writePool := pool.NewWorkerPool("write", pool.WithLogger(logger))
+
+	written := map[string]int{}
+	writtenMutex := &sync.Mutex{}
+	getWriter := func(source string) pool.FeedTransformer {
+		return func(res interface{}) pool.Runner {
+			return func(ctx context.Context) (interface{}, error) {
+				writtenMutex.Lock()
+				written[source]++
+				writtenMutex.Unlock()
+				return nil, nil
+			}
+		}
+	}
+	getRunner := func() pool.Runner {
+		return func(ctx context.Context) (interface{}, error) {
+			return struct{}{}, nil
+		}
+	}
+
+	if err := writePool.AddInputFeed("blocks", blockPool.Results(), getWriter("blocks")); err != nil {
+		t.Fatalf("Error adding feed: %v", err)
+	}
+	if err := writePool.AddGroupInputFeed("contracts", contractPool.Results(), map[string]pool.FeedTransformer{"a": getWriter("contracts"), "b": getWriter("contracts")}); err != nil {
+		t.Fatalf("Error adding feed: %v", err)
+	}
+	if err := writePool.AddInputFeed("blocks", blockPool.Results(), getWriter("blocks")); err == nil {
+		t.Error("Expected a duplicate feed name to be refused")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	blockPool.Start(ctx)
+	contractPool.Start(ctx)
+	writePool.Start(ctx)
+	defer blockPool.Stop()
+	defer contractPool.Stop()
+	defer writePool.Stop()
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(2)
+		blockPool.PushJob(getRunner(), wg)
+		wg.Add(3)
+		contractPool.PushJob(getRunner(), wg)
+	}
+	wg.Wait()
+	if written["blocks"] != 5 || written["contracts"] != 10 {
+		t.Errorf("Expected 5 block writes and 10 contract writes, but got %v", written)
+	}
+
+	//	Feeds can be detached, and attached, while the pool is running
+	if err := writePool.DetachFeed("contracts"); err != nil {
+		t.Fatalf("Error detaching feed: %v", err)
+	}
+	if _, ok := writePool.Insights()["feed:contracts"]; ok {
+		t.Error("Expected the detached feed to be gone from insights")
+	}
+	if err := writePool.DetachFeed("contracts"); err == nil {
+		t.Error("Expected detaching an unknown feed to fail")
+	}
+	if err := writePool.AddInputFeed("contracts", contractPool.Results(), getWriter("contracts")); err != nil {
+		t.Fatalf("Error re-adding feed: %v", err)
+	}
+	wg.Add(2)
+	contractPool.PushJob(getRunner(), wg)
+	wg.Wait()
+	if written["contracts"] != 11 {
+		t.Errorf("Expected the re-added feed to be consumed, but got %v", written)
+	}
+}
+
+// recordingMetrics is a util.Metrics that keeps every count and timing it is sent, keyed by name and tags
+type recordingMetrics struct {
+	mu      sync.Mutex
+	counts  map[string]int64
+	timings map[string][]time.Duration
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{counts: map[string]int64{}, timings: map[string][]time.Duration{}}
+}
+
+func metricKey(name string, tags []string) string {
+	return name + "|" + strings.Join(tags, ",")
+}
+
+func (m *recordingMetrics) Incr(name string, tags []string, rate float64) error {
+	return m.Count(name, 1, tags, rate)
+}
+
+func (m *recordingMetrics) Decr(name string, tags []string, rate float64) error {
+	return m.Count(name, -1, tags, rate)
+}
+
+func (m *recordingMetrics) Count(name string, value int64, tags []string, rate float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[metricKey(name, tags)] += value
+	return nil
+}
+
+func (m *recordingMetrics) Gauge(name string, value float64, tags []string, rate float64) error {
+	return nil
+}
+
+func (m *recordingMetrics) Histogram(name string, value float64, tags []string, rate float64) error {
+	return nil
+}
+
+func (m *recordingMetrics) Timing(name string, value time.Duration, tags []string, rate float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timings[metricKey(name, tags)] = append(m.timings[metricKey(name, tags)], value)
+	return nil
+}
+
+func (m *recordingMetrics) Close() error {
+	return nil
+}
+
+func (m *recordingMetrics) ServiceCheck(sc *statsd.ServiceCheck) error {
+	return nil
+}
+
+func (m *recordingMetrics) SimpleEvent(title, text string) error {
+	return nil
+}
+
+func (m *recordingMetrics) Event(e *statsd.Event) error {
+	return nil
+}
+
+func TestJobMetrics(t *testing.T) {
+	//	Instantiate logger
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+
+	metrics := newRecordingMetrics()
+	wp := pool.NewWorkerPool("fetch", pool.WithLogger(logger), pool.WithMetrics(metrics), pool.WithErrHandler(func(err error) {}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	wp.Start(ctx)
+	defer wp.Stop()
+
+	getRunner := func(delay time.Duration, err error) pool.Runner {
+		return func(ctx context.Context) (interface{}, error) {
+			time.Sleep(delay)
+			return nil, err
+		}
+	}
+
+	//	Group members are tagged with their key in the group map, and one-off jobs with their job key
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+	for i := 0; i < 2; i++ {
+		wp.PushGroup(map[string]pool.Runner{
+			"fetchBlock":    getRunner(50*time.Millisecond, nil),
+			"fetchReceipts": getRunner(0, errors.New("no receipts")),
+		}, wg)
+	}
+	wg.Add(1)
+	wp.PushJob(getRunner(0, nil), wg, pool.WithJobKey("fetchTraces"))
+	wg.Wait()
+
+	metrics.mu.Lock()
+	expectedCounts := map[string]int64{
+		"worker_pool.jobs|pool:fetch,job:fetchBlock,status:success":    2,
+		"worker_pool.jobs|pool:fetch,job:fetchReceipts,status:error":   2,
+		"worker_pool.jobs|pool:fetch,job:fetchTraces,status:success":   1,
+		"worker_pool.jobs|pool:fetch,job:fetchReceipts,status:success": 0,
+	}
+	for key, expected := range expectedCounts {
+		if metrics.counts[key] != expected {
+			t.Errorf("Expected count %d for [%s], but got %d", expected, key, metrics.counts[key])
+		}
+	}
+	durations := metrics.timings["worker_pool.job_duration|pool:fetch,job:fetchBlock"]
+	if len(durations) != 2 || durations[0] < 50*time.Millisecond {
+		t.Errorf("Expected two fetchBlock durations of at least 50ms, but got %v", durations)
+	}
+	if waits := metrics.timings["worker_pool.queue_wait|pool:fetch,job:fetchTraces"]; len(waits) != 1 {
+		t.Errorf("Expected one queue wait for fetchTraces, but got %v", waits)
+	}
+	metrics.mu.Unlock()
+
+	//	A client without distributions still gets its counts; wrapping the recorder hides its Timing method
+	countsOnly := newRecordingMetrics()
+	wp = pool.NewWorkerPool("write", pool.WithLogger(logger), pool.WithMetrics(struct{ util.Metrics }{countsOnly}))
+	wp.Start(ctx)
+	defer wp.Stop()
+	wg.Add(1)
+	wp.PushJob(getRunner(0, nil), wg)
+	wg.Wait()
+
+	countsOnly.mu.Lock()
+	defer countsOnly.mu.Unlock()
+	if count := countsOnly.counts["worker_pool.jobs|pool:write,job:job,status:success"]; count != 1 || len(countsOnly.timings) != 0 {
+		t.Errorf("Expected a job count and no timings, but got %d and %v", count, countsOnly.timings)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	//	Instantiate logger
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	logger := midLogger.Sugar()
+
+	tracer, exporter := tracing.NewInMemory("test")
+	pool1 := pool.NewWorkerPool("pool1", pool.WithLogger(logger), pool.WithOutputChannel(), pool.WithTracer(tracer))
+	pool2 := pool.NewWorkerPool("pool2", pool.WithLogger(logger), pool.WithTracer(tracer))
+	pool2.SetInputFeed(pool1.Results(), func(res interface{}) pool.Runner {
+		return func(ctx context.Context) (interface{}, error) {
+			return nil, errors.New("write failed")
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	pool1.Start(ctx)
+	pool2.Start(ctx)
+	defer pool1.Stop()
+	defer pool2.Stop()
+
+	getRunner := func() pool.Runner {
+		return func(ctx context.Context) (interface{}, error) {
+			return struct{}{}, nil
+		}
+	}
+
+	//	Every job of the block, in either pool, is traced as a child of the block's span
+	blockCtx, blockSpan := tracer.Start(ctx, "block")
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+	pool1.PushGroup(map[string]pool.Runner{"fetchBlock": getRunner(), "fetchReceipts": getRunner()}, wg, pool.WithTraceContext(blockCtx))
+	wg.Wait()
+	blockSpan.End()
+
+	var names []string
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "block" {
+			continue
+		}
+		names = append(names, span.Name)
+		if span.Parent.SpanID() != blockSpan.SpanContext().SpanID() || span.SpanContext.TraceID() != blockSpan.SpanContext().TraceID() {
+			t.Errorf("Expected span [%s] to be a child of the block span", span.Name)
+		}
+		if span.Name == "pool2/job" && span.Status.Description != "write failed" {
+			t.Errorf("Expected the error of span [%s] to be recorded, but got %+v", span.Name, span.Status)
+		}
+	}
+	sort.Strings(names)
+	if fmt.Sprint(names) != "[pool1/fetchBlock pool1/fetchReceipts pool2/job]" {
+		t.Errorf("Unexpected spans: %v", names)
+	}
+}
+
+func TestThrottler(t *testing.T) {
+	//	A burst of 2 per 100ms refills a token every 50ms, so 6 waits take about 200ms
+	throttler := pool.NewThrottler(2, 100*time.Millisecond)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := throttler.Wait(context.Background()); err != nil {
+			t.Fatalf("Unexpected error waiting for throttler: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond || elapsed > 400*time.Millisecond {
+		t.Errorf("Expected 6 waits to take about 200ms, but took %s", elapsed)
+	}
+
+	//	Waits end with their context
+	throttler.SetRate(1, time.Hour)
+	throttler.SetBurst(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := throttler.Wait(ctx); err == nil {
+		t.Error("Expected the wait to end with its context")
+	}
+
+	//	Stopping the throttler releases its waiters, and every wait that follows, with ErrThrottlerStopped
+	doneCh := make(chan error)
+	go func() {
+		doneCh <- throttler.Wait(context.Background())
+	}()
+	time.Sleep(20 * time.Millisecond)
+	throttler.Stop()
+	select {
+	case err := <-doneCh:
+		if !errors.Is(err, pool.ErrThrottlerStopped) {
+			t.Errorf("Expected the released waiter to get ErrThrottlerStopped, but got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected stopping the throttler to release its waiter")
+	}
+	if err := throttler.Wait(context.Background()); !errors.Is(err, pool.ErrThrottlerStopped) {
+		t.Errorf("Expected a stopped throttler to return ErrThrottlerStopped, but got %v", err)
+	}
+
+	//	A pool no longer paced by its stopped throttler still runs its jobs
+	midLogger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("Error instantiating logger: %v", err)
+	}
+	wp := pool.NewWorkerPool("throttled", pool.WithLogger(midLogger.Sugar()), pool.WithThrottler(throttler))
+	wp.Start(context.Background())
+	defer wp.Stop()
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+	for i := 0; i < 3; i++ {
+		wp.PushJob(func(ctx context.Context) (interface{}, error) {
+			return nil, nil
+		}, wg, pool.WithEmitter(func(e pool.Emission) {
+			if e.Err != nil {
+				t.Errorf("Expected the job to run, but got %v", e.Err)
+			}
+		}))
+	}
+	wg.Wait()
+}
+
+func TestThrottlerMetrics(t *testing.T) {
+	//	Every wait is counted and timed, including those that had a token straight away
+	metrics := newRecordingMetrics()
+	throttler := pool.NewThrottler(1, 50*time.Millisecond, pool.WithThrottlerMetrics(metrics, "rpc"))
+	for i := 0; i < 3; i++ {
+		if err := throttler.Wait(context.Background()); err != nil {
+			t.Fatalf("Unexpected error waiting for throttler: %v", err)
+		}
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	if waits := metrics.counts["throttler.waits|throttler:rpc"]; waits != 3 {
+		t.Errorf("Expected 3 waits to be counted, but got %d", waits)
+	}
+	timings := metrics.timings["throttler.wait|throttler:rpc"]
+	if len(timings) != 3 || timings[0] > 10*time.Millisecond || timings[2] < 30*time.Millisecond {
+		t.Errorf("Expected an immediate wait followed by 2 throttled ones, but got %v", timings)
+	}
+}
@@ -2,9 +2,17 @@ package pool
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrJobTimeout is matched (via errors.Is) by the error reported for a job that exceeded its timeout
+var ErrJobTimeout = errors.New("job timed out")
+
 // Runner is an executable function that runs as a job
 type Runner func(ctx context.Context) (interface{}, error)
 
@@ -17,23 +25,58 @@ type FeedTransformer func(res interface{}) Runner
 // ResultSet is a set of results accumulated from a group
 type ResultSet map[string]interface{}
 
+// Emission is the final outcome of a one-off job or a group; Err is set (and Payload is nil) if the job or group
+// failed, or if the job was rejected or abandoned by the pool. BestEffort groups emit a PartialResultSet payload
+type Emission struct {
+	Payload interface{}
+	Err     error
+	Receipt *sync.WaitGroup
+	//	TraceContext is the trace context the job or group was pushed with, for downstream work to carry on
+	TraceContext trace.SpanContext
+}
+
+// Emitter receives every Emission of a pool, synchronously and before the receipt of the job is released, so that an
+// emitter may add downstream work to the receipt without it ever reaching zero in between
+type Emitter func(e Emission)
+
 type result struct {
-	payload interface{}
-	wg      *sync.WaitGroup
+	payload  interface{}
+	wg       *sync.WaitGroup
+	priority Priority
+	traceCtx trace.SpanContext
 }
 
 // job is an internal enclosure for a Runner that specifies and ID and group info
 type job struct {
-	fn        Runner
-	id        string
-	groupID   string
-	receiptWg *sync.WaitGroup
+	fn         Runner
+	id         string
+	groupID    string
+	receiptWg  *sync.WaitGroup
+	timeout    time.Duration
+	attempts   int
+	firstRun   time.Time
+	retryDelay time.Duration
+	descriptor string
+	priority   Priority
+	emitter    Emitter
+	key        string
+	enqueuedAt time.Time
+	traceCtx   trace.SpanContext
 }
 
 // group is a collection of jobs meant to be run in parallel with the result processed as a unit
 type group struct {
 	results    map[string]interface{}
+	errors     map[string]error
 	pipelineWg *sync.WaitGroup
 	cursor     int
 	jobCount   int
+	policy     GroupPolicy
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+// newTimeoutError describes a job that exceeded its timeout
+func newTimeoutError(job *job, timeout time.Duration) error {
+	return fmt.Errorf("job [%s] exceeded timeout of %s: %w", job.id, timeout, ErrJobTimeout)
 }
@@ -0,0 +1,90 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/retry"
+)
+
+const (
+	defaultRetryAttempts   = 3
+	defaultRetryBackoff    = 500 * time.Millisecond
+	defaultRetryMaxBackoff = 30 * time.Second
+)
+
+// DefaultRetryPolicy returns a policy of 3 attempts with jittered backoff starting at 500ms, up to 30s
+func DefaultRetryPolicy() retry.Policy {
+	policy := retry.DecorrelatedJitter(defaultRetryBackoff, defaultRetryMaxBackoff)
+	policy.MaxAttempts = defaultRetryAttempts
+	return policy
+}
+
+// retryPolicyOf completes a policy supplied to WithJobRetry(): a policy bounded neither in attempts nor in time is
+// limited to the default number of attempts, so that failing jobs are not retried forever, and a policy without a
+// backoff gets the default one
+func retryPolicyOf(policy retry.Policy) retry.Policy {
+	if policy.MaxAttempts <= 0 && policy.MaxElapsed <= 0 {
+		policy.MaxAttempts = defaultRetryAttempts
+	}
+	if policy.Backoff == nil {
+		policy.Backoff = DefaultRetryPolicy().Backoff
+	}
+	return policy
+}
+
+// retryDelay decides whether a failed job should be re-enqueued, and after how long; jobs are never retried once the
+// pool is stopping, after a panic, nor after an error marked with retry.Permanent()
+func (wp *WorkerPool) retryDelay(ctx context.Context, job *job, err error) (time.Duration, bool) {
+	if err == nil || wp.retryPolicy == nil || ctx.Err() != nil {
+		return 0, false
+	}
+	policy := wp.retryPolicy
+	if policy.MaxAttempts > 0 && job.attempts >= policy.MaxAttempts || errors.Is(err, ErrGroupCancelled) {
+		return 0, false
+	}
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) || retry.IsPermanent(err) {
+		return 0, false
+	}
+
+	delay := policy.Backoff.Delay(job.attempts, job.retryDelay)
+	if policy.MaxElapsed > 0 && time.Since(job.firstRun)+delay > policy.MaxElapsed {
+		return 0, false
+	}
+	return delay, true
+}
+
+// scheduleRetry re-enqueues a failed job once its backoff has elapsed; the job keeps its ID, group and receipt, so
+// group jobs are accumulated into the same group on completion
+func (wp *WorkerPool) scheduleRetry(ctx context.Context, job job, err error, delay time.Duration) {
+	wp.logger.Warnf("Job [%s] in pool [%s] failed on attempt %d; retrying in %s", job.id, wp.id, job.attempts, delay)
+	if wp.retryPolicy.OnRetry != nil {
+		wp.retryPolicy.OnRetry(job.attempts, err, delay)
+	}
+	job.retryDelay = delay
+	wp.incrRetrying()
+
+	wp.workerWg.Add(1)
+	go func() {
+		defer wp.workerWg.Done()
+		defer wp.decrRetrying()
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			wp.finish(&job, nil, ctx.Err())
+			return
+		case <-timer.C:
+		}
+		job.enqueuedAt = time.Now()
+
+		select {
+		case <-ctx.Done():
+			wp.finish(&job, nil, ctx.Err())
+		case wp.laneFor(&job) <- job:
+		}
+	}()
+}
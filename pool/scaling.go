@@ -0,0 +1,115 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	defaultAutoscaleInterval = 5 * time.Second
+)
+
+// AutoscalerConfig bounds and paces the autoscaler enabled with WithAutoscaler()
+type AutoscalerConfig struct {
+	//	MinBandwidth is the floor the pool may shrink to; defaults to 1
+	MinBandwidth int
+	//	MaxBandwidth is the ceiling the pool may grow to
+	MaxBandwidth int
+	//	Interval is how often the workload is assessed; defaults to 5s
+	Interval time.Duration
+}
+
+// SetBandwidth scales the number of job and error workers up or down; queued jobs are kept, and workers removed by
+// a scale-down finish their current job before exiting
+func (wp *WorkerPool) SetBandwidth(bandwidth int) {
+	if bandwidth < 1 {
+		bandwidth = 1
+	}
+
+	wp.scaleMu.Lock()
+	defer wp.scaleMu.Unlock()
+
+	previous := wp.bandwidth
+	wp.bandwidth = bandwidth
+
+	//	workers are only resized while the pool is running; otherwise the new bandwidth applies on Start()
+	if wp.runCtx == nil || wp.runCtx.Err() != nil {
+		return
+	}
+
+	for len(wp.jobWorkers) < bandwidth {
+		wp.jobWorkers = append(wp.jobWorkers, wp.startJobWorker(wp.runCtx))
+		wp.errWorkers = append(wp.errWorkers, wp.startErrorWorker(wp.runCtx))
+	}
+	for len(wp.jobWorkers) > bandwidth {
+		last := len(wp.jobWorkers) - 1
+		close(wp.jobWorkers[last])
+		close(wp.errWorkers[last])
+		wp.jobWorkers = wp.jobWorkers[:last]
+		wp.errWorkers = wp.errWorkers[:last]
+	}
+
+	if previous != bandwidth {
+		wp.logger.Infof("Bandwidth of worker pool [%s] changed from %d to %d", wp.id, previous, bandwidth)
+	}
+}
+
+// startAutoscaler spins up a worker that periodically resizes the pool, if an autoscaler has been specified with
+// the WithAutoscaler() option
+func (wp *WorkerPool) startAutoscaler(ctx context.Context) {
+	if wp.autoscaler == nil {
+		return
+	}
+
+	interval := wp.autoscaler.Interval
+	if interval <= 0 {
+		interval = defaultAutoscaleInterval
+	}
+
+	wp.workerWg.Add(1)
+	go func() {
+		defer wp.workerWg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				insights := wp.Insights()
+				wp.SetBandwidth(wp.autoscaler.target(insights["bandwidth"], insights["jobCh"], insights["inProgress"], insights["waiting"]))
+			}
+		}
+	}()
+}
+
+// target computes the bandwidth the pool should have, given its current bandwidth, the number of queued jobs, the
+// number of jobs in progress and the number of jobs waiting on the throttler; the pool at most doubles when saturated,
+// and sheds half of its idle workers when idle. A pool with jobs waiting on the throttler is throttle-bound, and never
+// grows, as more workers would only wait too
+func (cfg *AutoscalerConfig) target(bandwidth, queued, inProgress, waiting int) int {
+	target := bandwidth
+	busy := inProgress + waiting
+	switch {
+	case queued > 0 && busy >= bandwidth && waiting == 0:
+		grow := queued
+		if grow > bandwidth {
+			grow = bandwidth
+		}
+		target = bandwidth + grow
+	case queued == 0 && busy < bandwidth/2:
+		target = bandwidth - (bandwidth-busy)/2
+	}
+
+	minBandwidth := cfg.MinBandwidth
+	if minBandwidth < 1 {
+		minBandwidth = 1
+	}
+	if cfg.MaxBandwidth > 0 && target > cfg.MaxBandwidth {
+		target = cfg.MaxBandwidth
+	}
+	if target < minBandwidth {
+		target = minBandwidth
+	}
+	return target
+}
@@ -0,0 +1,31 @@
+package pool
+
+import "testing"
+
+func TestAutoscalerTarget(t *testing.T) {
+	cfg := &AutoscalerConfig{MinBandwidth: 2, MaxBandwidth: 12}
+	tests := []struct {
+		name                                   string
+		bandwidth, queued, inProgress, waiting int
+		expected                               int
+	}{
+		//	Saturated pools grow by their backlog, at most doubling, up to the ceiling
+		{name: "grow by backlog", bandwidth: 4, queued: 2, inProgress: 4, expected: 6},
+		{name: "grow at most double", bandwidth: 4, queued: 10, inProgress: 4, expected: 8},
+		{name: "grow up to ceiling", bandwidth: 8, queued: 10, inProgress: 8, expected: 12},
+		//	Workers waiting on the throttler are busy, but more of them would only wait too
+		{name: "throttle-bound", bandwidth: 4, queued: 10, inProgress: 1, waiting: 3, expected: 4},
+		{name: "throttle-bound is not idle", bandwidth: 8, inProgress: 1, waiting: 6, expected: 8},
+		//	Idle pools shed half of their idle workers, down to the floor
+		{name: "shrink", bandwidth: 8, inProgress: 2, expected: 5},
+		{name: "shrink down to floor", bandwidth: 3, expected: 2},
+		{name: "steady", bandwidth: 8, queued: 0, inProgress: 6, expected: 8},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := cfg.target(test.bandwidth, test.queued, test.inProgress, test.waiting); got != test.expected {
+				t.Errorf("Expected a target of %d, got %d", test.expected, got)
+			}
+		})
+	}
+}
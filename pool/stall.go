@@ -0,0 +1,79 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// startStallDetector spins up a worker that watches for the pool making no progress while it has work, if a stall
+// threshold has been specified with the WithStallDetector() option; a stall is logged once, with the depths of the
+// pool's queues to point at the cause, and reported as metrics for as long as it lasts
+func (wp *WorkerPool) startStallDetector(ctx context.Context) {
+	if wp.stallThreshold <= 0 {
+		return
+	}
+
+	wp.workerWg.Add(1)
+	go func() {
+		defer wp.workerWg.Done()
+		ticker := time.NewTicker(wp.stallThreshold / 2)
+		defer ticker.Stop()
+
+		tags := []string{fmt.Sprintf("pool:%s", wp.id)}
+		lastProgress := wp.progress()
+		lastChange := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			//	any settled job counts as progress, and an idle pool can't be stalled
+			progress := wp.progress()
+			if progress != lastProgress || wp.idle() {
+				lastProgress = progress
+				lastChange = time.Now()
+				if wp.setStalled(false) {
+					wp.logger.Infof("Worker pool [%s] is making progress again", wp.id)
+				}
+				continue
+			}
+
+			stalledFor := time.Since(lastChange)
+			if stalledFor < wp.stallThreshold {
+				continue
+			}
+			if !wp.setStalled(true) {
+				insights := wp.Insights()
+				wp.logger.Warnf(
+					"Worker pool [%s] has made no progress for %s; %d jobs pending, %d in progress, %d queued, %d results awaiting a consumer (of %d buffered)",
+					wp.id, stalledFor.Round(time.Second), insights["pending"], insights["inProgress"], insights["jobCh"], insights["resultCh"], cap(wp.resultCh),
+				)
+				if wp.metrics != nil {
+					wp.metrics.Incr("worker_pool.stalls", tags, 1.0)
+				}
+			}
+			if wp.metrics != nil {
+				wp.metrics.Gauge("worker_pool.stalled_seconds", stalledFor.Seconds(), tags, 1.0)
+			}
+		}
+	}()
+}
+
+// progress counts the jobs settled by the pool so far
+func (wp *WorkerPool) progress() int {
+	wp.pendingMu.Lock()
+	defer wp.pendingMu.Unlock()
+	return wp.countSettled
+}
+
+// setStalled records whether the pool is stalled, returning the previous state
+func (wp *WorkerPool) setStalled(stalled bool) bool {
+	wp.pendingMu.Lock()
+	defer wp.pendingMu.Unlock()
+	previous := wp.stalled
+	wp.stalled = stalled
+	return previous
+}
@@ -2,63 +2,177 @@ package pool
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
+
+	"github.com/coherentopensource/go-service-framework/util"
+	"golang.org/x/time/rate"
 )
 
+// Limiter paces the jobs of a pool; Throttler is the local implementation, and any limiter with the same Wait method
+// (such as rate_limiter.RedisLimiter, shared across replicas) can take its place in WithThrottler()
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// ErrThrottlerStopped is returned by Wait once the throttler is stopped
+var ErrThrottlerStopped = errors.New("throttler stopped")
+
+// Throttler is a token bucket that paces the jobs of a pool: it holds up to burst tokens, refilled smoothly at a rate
+// of burst tokens per duration, and every job takes a token before it runs
 type Throttler struct {
-	burst      int
-	duration   time.Duration
-	ticker     *time.Ticker
-	tokens     int
-	tokenMu    *sync.Mutex
+	limiter    *rate.Limiter
+	stopMu     *sync.Mutex
 	ctx        context.Context
 	cancelFunc context.CancelFunc
+	metrics    util.Metrics
+	name       string
 }
 
-func NewThrottler(burst int, duration time.Duration) *Throttler {
-	return &Throttler{
-		burst:    burst,
-		duration: duration,
-		tokenMu:  &sync.Mutex{},
+// ThrottlerOpt configures a Throttler
+type ThrottlerOpt func(t *Throttler)
+
+// WithThrottlerMetrics reports the number of waits, and the time spent waiting if the client supports distributions,
+// tagged by the given name
+func WithThrottlerMetrics(metrics util.Metrics, name string) ThrottlerOpt {
+	return func(t *Throttler) {
+		t.metrics = metrics
+		t.name = name
 	}
 }
 
+// NewThrottler instantiates a throttler allowing burst jobs per duration; the bucket starts full
+func NewThrottler(burst int, duration time.Duration, opts ...ThrottlerOpt) *Throttler {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &Throttler{
+		limiter:    rate.NewLimiter(rateOf(burst, duration), burst),
+		stopMu:     &sync.Mutex{},
+		ctx:        ctx,
+		cancelFunc: cancel,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Start ties the throttler to a context; once it is cancelled, the throttler is stopped. The throttler refills on its
+// own, so starting it is optional
 func (t *Throttler) Start(ctx context.Context) error {
+	t.stopMu.Lock()
+	defer t.stopMu.Unlock()
+	t.cancelFunc()
 	t.ctx, t.cancelFunc = context.WithCancel(ctx)
-	t.tokens = t.burst
-	t.ticker = time.NewTicker(t.duration)
-
-	go func() {
-		for {
-			select {
-			case <-t.ctx.Done():
-				t.ticker.Stop()
-				return
-			case <-t.ticker.C:
-				t.tokenMu.Lock()
-				t.tokens = t.burst
-				t.tokenMu.Unlock()
-			}
-		}
-	}()
-
 	return nil
 }
 
+// Stop stops the throttler, releasing every waiter with ErrThrottlerStopped; a pool treats a stopped throttler as no
+// throttler at all
 func (t *Throttler) Stop() {
+	t.stopMu.Lock()
+	defer t.stopMu.Unlock()
 	t.cancelFunc()
 }
 
-func (t *Throttler) WaitForGo() {
-	for {
-		t.tokenMu.Lock()
-		if t.tokens > 0 {
-			t.tokens--
-			t.tokenMu.Unlock()
-			break
+// Wait blocks until a token is available, or until ctx is done, in which case ctx's error is returned; once the
+// throttler is stopped, Wait returns ErrThrottlerStopped
+func (t *Throttler) Wait(ctx context.Context) error {
+	t.stopMu.Lock()
+	stopCtx := t.ctx
+	t.stopMu.Unlock()
+	if stopCtx.Err() != nil {
+		return ErrThrottlerStopped
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//	reserve a token, then wait out its delay; the reservation is handed back if the wait is cut short
+	started := time.Now()
+	reservation := t.limiter.Reserve()
+	if !reservation.OK() {
+		return fmt.Errorf("throttler burst of %d does not allow a single job", t.limiter.Burst())
+	}
+	delay := reservation.Delay()
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			reservation.Cancel()
+			return ctx.Err()
+		case <-stopCtx.Done():
+			reservation.Cancel()
+			return ErrThrottlerStopped
 		}
-		t.tokenMu.Unlock()
-		time.Sleep(10 * time.Millisecond)
 	}
+	t.observe(time.Since(started))
+	return nil
+}
+
+// observe reports a wait to the metrics client of the throttler, if any
+func (t *Throttler) observe(wait time.Duration) {
+	if t.metrics == nil {
+		return
+	}
+	tags := []string{fmt.Sprintf("throttler:%s", t.name)}
+	t.metrics.Incr("throttler.waits", tags, 1.0)
+	if distributions, ok := t.metrics.(util.DistributionMetrics); ok {
+		distributions.Timing("throttler.wait", wait, tags, 1.0)
+	}
+}
+
+// WaitForGo blocks until a token is available, or until the throttler is stopped
+//
+// Deprecated: use Wait, which also returns when its context is done
+func (t *Throttler) WaitForGo() {
+	t.Wait(context.Background())
+}
+
+// SetRate changes the refill rate to events per duration, taking effect for the waits that follow
+func (t *Throttler) SetRate(events int, duration time.Duration) {
+	t.limiter.SetLimit(rateOf(events, duration))
+}
+
+// SetBurst changes the capacity of the bucket
+func (t *Throttler) SetBurst(burst int) {
+	t.limiter.SetBurst(burst)
+}
+
+// Rate reports the refill rate, in tokens per second
+func (t *Throttler) Rate() float64 {
+	return float64(t.limiter.Limit())
+}
+
+// Burst reports the capacity of the bucket
+func (t *Throttler) Burst() int {
+	return t.limiter.Burst()
+}
+
+func rateOf(events int, duration time.Duration) rate.Limit {
+	if events <= 0 || duration <= 0 {
+		return rate.Inf
+	}
+	return rate.Limit(float64(events) / duration.Seconds())
+}
+
+// throttle waits for the pool's throttler, if any, to let a job run, reporting the time spent waiting
+func (wp *WorkerPool) throttle(ctx context.Context, job *job) error {
+	if wp.throttler == nil {
+		return nil
+	}
+
+	wp.incrWaiting()
+	defer wp.decrWaiting()
+	started := time.Now()
+	err := wp.throttler.Wait(ctx)
+	wp.observeThrottle(job, time.Since(started))
+	//	a stopped throttler no longer paces the pool
+	if errors.Is(err, ErrThrottlerStopped) {
+		return nil
+	}
+	return err
 }
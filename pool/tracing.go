@@ -0,0 +1,46 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan starts a span for a job attempt, as a child of the trace context the job was pushed (or fed) with, if
+// the pool has a tracer; the Runner receives the span in its context
+func (wp *WorkerPool) startSpan(ctx context.Context, job *job) (context.Context, trace.Span) {
+	if wp.tracer == nil {
+		return ctx, nil
+	}
+
+	if job.traceCtx.IsValid() {
+		ctx = trace.ContextWithSpanContext(ctx, job.traceCtx)
+	}
+	key := job.key
+	if key == "" {
+		key = defaultJobKey
+	}
+	return wp.tracer.Start(ctx, fmt.Sprintf("%s/%s", wp.id, key), trace.WithAttributes(
+		attribute.String("pool.id", wp.id),
+		attribute.String("job.key", key),
+		attribute.String("job.id", job.id),
+		attribute.String("job.group_id", job.groupID),
+		attribute.String("job.descriptor", job.descriptor),
+		attribute.Int("job.attempt", job.attempts+1),
+	))
+}
+
+// endSpan ends the span of a job attempt, recording its error, if any
+func (wp *WorkerPool) endSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,232 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrTypeMismatch is reported for payloads of a typed feed that are not of the type the feed carries, e.g. those of a
+// legacy pool bridged with FeedOf(); the job the payload was meant for fails with this error instead of running
+var ErrTypeMismatch = errors.New("payload type mismatch")
+
+// TypedRunner is a Runner whose output type is checked at compile time
+type TypedRunner[Out any] func(ctx context.Context) (Out, error)
+
+// TypedTransformer transforms a typed input into a TypedRunner
+type TypedTransformer[In, Out any] func(in In) TypedRunner[Out]
+
+// TypedResultSet is the typed counterpart of ResultSet, keyed by the job names of a group
+type TypedResultSet[Out any] map[string]Out
+
+// Feed is a typed view over the results channel of a pool; feeds are produced by Stage.Results(),
+// GroupStage.Results() or FeedOf(), and consumed by SetInputFeed()/SetGroupInputFeed() on a downstream stage
+type Feed[T any] struct {
+	ch     <-chan result
+	decode func(payload interface{}) (T, error)
+}
+
+// FeedOf wraps the results of an untyped WorkerPool as a typed feed, for bridging legacy pools into typed stages;
+// payloads that are not of type T fail their jobs downstream with ErrTypeMismatch
+func FeedOf[T any](wp *WorkerPool) Feed[T] {
+	return Feed[T]{ch: wp.Results(), decode: assertAs[T]}
+}
+
+// Chan exposes the underlying untyped channel, so that a typed feed can be consumed by a legacy WorkerPool
+func (f Feed[T]) Chan() <-chan result {
+	return f.ch
+}
+
+// Stage is a type-safe wrapper around a WorkerPool whose one-off jobs consume values of type In and produce
+// values of type Out
+type Stage[In, Out any] struct {
+	wp *WorkerPool
+}
+
+// NewStage instantiates a typed stage backed by a new worker pool
+func NewStage[In, Out any](id string, opts ...opt) *Stage[In, Out] {
+	return &Stage[In, Out]{wp: NewWorkerPool(id, opts...)}
+}
+
+// Pool gives access to the underlying untyped worker pool
+func (s *Stage[In, Out]) Pool() *WorkerPool {
+	return s.wp
+}
+
+// Start starts the underlying worker pool
+func (s *Stage[In, Out]) Start(ctx context.Context) error {
+	return s.wp.Start(ctx)
+}
+
+// Stop stops the underlying worker pool
+func (s *Stage[In, Out]) Stop() {
+	s.wp.Stop()
+}
+
+// Insights reports the insights of the underlying worker pool
+func (s *Stage[In, Out]) Insights() map[string]int {
+	return s.wp.Insights()
+}
+
+// PushJob queues a typed one-off job for execution
+func (s *Stage[In, Out]) PushJob(fn TypedRunner[Out], wg *sync.WaitGroup, opts ...PushOpt) {
+	s.wp.PushJob(fn.untyped(), wg, opts...)
+}
+
+// SetInputFeed configures the stage to receive jobs from a typed feed, with each transformer producing one job
+// per input
+func (s *Stage[In, Out]) SetInputFeed(feed Feed[In], transformers ...TypedTransformer[In, Out]) {
+	untyped := make([]FeedTransformer, 0, len(transformers))
+	for _, transformer := range transformers {
+		untyped = append(untyped, transformer.untyped(feed.decode))
+	}
+	s.wp.SetInputFeed(feed.ch, untyped...)
+}
+
+// AddInputFeed attaches a named typed feed to the stage, alongside any other feeds, with each transformer producing
+// one job per input
+func (s *Stage[In, Out]) AddInputFeed(name string, feed Feed[In], transformers ...TypedTransformer[In, Out]) error {
+	untyped := make([]FeedTransformer, 0, len(transformers))
+	for _, transformer := range transformers {
+		untyped = append(untyped, transformer.untyped(feed.decode))
+	}
+	return s.wp.AddInputFeed(name, feed.ch, untyped...)
+}
+
+// DetachFeed stops the stage from consuming a named feed
+func (s *Stage[In, Out]) DetachFeed(name string) error {
+	return s.wp.DetachFeed(name)
+}
+
+// Results gives typed access to the results of the stage; requires that the WithOutputChannel() option be passed
+// to the constructor for proper functionality
+func (s *Stage[In, Out]) Results() Feed[Out] {
+	return Feed[Out]{ch: s.wp.Results(), decode: assertAs[Out]}
+}
+
+// GroupStage is a type-safe wrapper around a WorkerPool whose jobs run in groups; each group member produces a
+// value of type Out and the group as a whole produces a TypedResultSet[Out]
+type GroupStage[In, Out any] struct {
+	wp *WorkerPool
+}
+
+// NewGroupStage instantiates a typed group stage backed by a new worker pool
+func NewGroupStage[In, Out any](id string, opts ...opt) *GroupStage[In, Out] {
+	return &GroupStage[In, Out]{wp: NewWorkerPool(id, opts...)}
+}
+
+// Pool gives access to the underlying untyped worker pool
+func (s *GroupStage[In, Out]) Pool() *WorkerPool {
+	return s.wp
+}
+
+// Start starts the underlying worker pool
+func (s *GroupStage[In, Out]) Start(ctx context.Context) error {
+	return s.wp.Start(ctx)
+}
+
+// Stop stops the underlying worker pool
+func (s *GroupStage[In, Out]) Stop() {
+	s.wp.Stop()
+}
+
+// Insights reports the insights of the underlying worker pool
+func (s *GroupStage[In, Out]) Insights() map[string]int {
+	return s.wp.Insights()
+}
+
+// PushGroup queues a typed group of runners for execution
+func (s *GroupStage[In, Out]) PushGroup(fns map[string]TypedRunner[Out], wg *sync.WaitGroup, opts ...PushOpt) {
+	untyped := make(map[string]Runner, len(fns))
+	for key, fn := range fns {
+		untyped[key] = fn.untyped()
+	}
+	s.wp.PushGroup(untyped, wg, opts...)
+}
+
+// SetGroupInputFeed configures the stage to receive groups from a typed feed, with each transformer producing one
+// member of the group per input
+func (s *GroupStage[In, Out]) SetGroupInputFeed(feed Feed[In], groupMap map[string]TypedTransformer[In, Out]) {
+	untyped := make(map[string]FeedTransformer, len(groupMap))
+	for key, transformer := range groupMap {
+		untyped[key] = transformer.untyped(feed.decode)
+	}
+	s.wp.SetGroupInputFeed(feed.ch, untyped)
+}
+
+// AddGroupInputFeed attaches a named typed feed to the stage, alongside any other feeds, with each transformer
+// producing one member of the group per input
+func (s *GroupStage[In, Out]) AddGroupInputFeed(name string, feed Feed[In], groupMap map[string]TypedTransformer[In, Out]) error {
+	untyped := make(map[string]FeedTransformer, len(groupMap))
+	for key, transformer := range groupMap {
+		untyped[key] = transformer.untyped(feed.decode)
+	}
+	return s.wp.AddGroupInputFeed(name, feed.ch, untyped)
+}
+
+// DetachFeed stops the stage from consuming a named feed
+func (s *GroupStage[In, Out]) DetachFeed(name string) error {
+	return s.wp.DetachFeed(name)
+}
+
+// Results gives typed access to the group results of the stage; requires that the WithOutputChannel() option be
+// passed to the constructor for proper functionality
+func (s *GroupStage[In, Out]) Results() Feed[TypedResultSet[Out]] {
+	return Feed[TypedResultSet[Out]]{ch: s.wp.Results(), decode: decodeResultSet[Out]}
+}
+
+// untyped adapts a TypedRunner to the untyped Runner signature
+func (fn TypedRunner[Out]) untyped() Runner {
+	return func(ctx context.Context) (interface{}, error) {
+		return fn(ctx)
+	}
+}
+
+// untyped adapts a TypedTransformer to the untyped FeedTransformer signature, using the decoder of the feed
+// it is attached to; a payload that can't be decoded yields a Runner failing with the decoding error, so that the
+// mismatch is reported by the pool, and the job's receipt released, like any other failure
+func (t TypedTransformer[In, Out]) untyped(decode func(payload interface{}) (In, error)) FeedTransformer {
+	return func(res interface{}) Runner {
+		in, err := decode(res)
+		if err != nil {
+			return func(ctx context.Context) (interface{}, error) {
+				return nil, err
+			}
+		}
+		return t(in).untyped()
+	}
+}
+
+// assertAs decodes a payload by type assertion; a nil payload decodes as the zero value of T
+func assertAs[T any](payload interface{}) (T, error) {
+	out, ok := payload.(T)
+	if !ok && payload != nil {
+		return out, fmt.Errorf("%w: expected %s, got %T", ErrTypeMismatch, reflect.TypeOf((*T)(nil)).Elem(), payload)
+	}
+	return out, nil
+}
+
+// decodeResultSet converts an untyped ResultSet into its typed counterpart; the successful results of a
+// PartialResultSet are decoded the same way
+func decodeResultSet[Out any](payload interface{}) (TypedResultSet[Out], error) {
+	var set ResultSet
+	switch payload := payload.(type) {
+	case ResultSet:
+		set = payload
+	case PartialResultSet:
+		set = payload.Results
+	default:
+		return nil, fmt.Errorf("%w: expected a result set, got %T", ErrTypeMismatch, payload)
+	}
+	out := make(TypedResultSet[Out], len(set))
+	for key, val := range set {
+		decoded, err := assertAs[Out](val)
+		if err != nil {
+			return nil, fmt.Errorf("result [%s]: %w", key, err)
+		}
+		out[key] = decoded
+	}
+	return out, nil
+}
@@ -0,0 +1,72 @@
+package progress
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	postgresChunkSize = 10000
+)
+
+// BlockProgress is a row of the table backing PostgresStore
+type BlockProgress struct {
+	Key         string `gorm:"primaryKey"`
+	Block       uint64 `gorm:"primaryKey;autoIncrement:false"`
+	CompletedAt time.Time
+}
+
+// PostgresStore records block completion in a Postgres table, one row per completed block; unlike RedisStore, it
+// keeps the time each block completed
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresStore instantiates a store on the supplied connection, creating its table if needed
+func NewPostgresStore(db *gorm.DB) (*PostgresStore, error) {
+	if err := db.AutoMigrate(&BlockProgress{}); err != nil {
+		return nil, err
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+func (s *PostgresStore) MarkComplete(ctx context.Context, key string, block uint64) error {
+	row := BlockProgress{Key: key, Block: block, CompletedAt: time.Now()}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&row).Error
+}
+
+func (s *PostgresStore) Clear(ctx context.Context, key string, from, to uint64) error {
+	return s.db.WithContext(ctx).Where("key = ? AND block >= ? AND block < ?", key, from, to).Delete(&BlockProgress{}).Error
+}
+
+func (s *PostgresStore) Missing(ctx context.Context, key string, from, to uint64) ([]uint64, error) {
+	missing := []uint64{}
+	for start := from; start < to; start += postgresChunkSize {
+		end := start + postgresChunkSize
+		if end > to {
+			end = to
+		}
+
+		var completed []uint64
+		err := s.db.WithContext(ctx).Model(&BlockProgress{}).
+			Where("key = ? AND block >= ? AND block < ?", key, start, end).
+			Order("block").
+			Pluck("block", &completed).Error
+		if err != nil {
+			return nil, err
+		}
+
+		next := 0
+		for block := start; block < end; block++ {
+			if next < len(completed) && completed[next] == block {
+				next++
+				continue
+			}
+			missing = append(missing, block)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,109 @@
+package progress_test
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/coherentopensource/go-service-framework/progress"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newMockStore returns a store on a mocked connection, on which the table already exists
+func newMockStore(t *testing.T) (*progress.PostgresStore, sqlmock.Sqlmock) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error opening mock connection: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{SkipDefaultTransaction: true, Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Error opening gorm connection: %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM information_schema.tables")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE "block_progresses"`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	store, err := progress.NewPostgresStore(db)
+	if err != nil {
+		t.Fatalf("Error instantiating store: %v", err)
+	}
+	return store, mock
+}
+
+func TestPostgresStore(t *testing.T) {
+	ctx := context.Background()
+	upsert := regexp.QuoteMeta(`INSERT INTO "block_progresses" ("key","block","completed_at") VALUES ($1,$2,$3) ` +
+		`ON CONFLICT ("key","block") DO UPDATE SET "completed_at"="excluded"."completed_at"`)
+	selectBlocks := regexp.QuoteMeta(`SELECT "block" FROM "block_progresses" WHERE key = $1 AND block >= $2 AND block < $3 ORDER BY block`)
+	blocks := func(values ...uint64) *sqlmock.Rows {
+		rows := sqlmock.NewRows([]string{"block"})
+		for _, value := range values {
+			rows.AddRow(value)
+		}
+		return rows
+	}
+
+	t.Run("mark complete", func(t *testing.T) {
+		//	Marking a block again updates its completion time rather than failing on the existing row
+		store, mock := newMockStore(t)
+		mock.ExpectExec(upsert).WithArgs("eth-blocks", 12, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(upsert).WithArgs("eth-blocks", 12, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		for i := 0; i < 2; i++ {
+			if err := store.MarkComplete(ctx, "eth-blocks", 12); err != nil {
+				t.Errorf("Unexpected error marking block 12 complete: %v", err)
+			}
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		//	Gaps between the completed blocks are reported, along with blocks past the last completed one
+		store, mock := newMockStore(t)
+		mock.ExpectQuery(selectBlocks).WithArgs("eth-blocks", 10, 16).WillReturnRows(blocks(10, 11, 13))
+		missing, err := store.Missing(ctx, "eth-blocks", 10, 16)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if fmt.Sprint(missing) != "[12 14 15]" {
+			t.Errorf("Unexpected missing blocks: %v", missing)
+		}
+
+		//	A key with nothing recorded is missing every block
+		mock.ExpectQuery(selectBlocks).WithArgs("eth-traces", 10, 12).WillReturnRows(blocks())
+		missing, _ = store.Missing(ctx, "eth-traces", 10, 12)
+		if fmt.Sprint(missing) != "[10 11]" {
+			t.Errorf("Unexpected missing blocks for another key: %v", missing)
+		}
+
+		//	Long ranges are read in chunks
+		mock.ExpectQuery(selectBlocks).WithArgs("eth-blocks", 0, 10000).WillReturnRows(blocks())
+		mock.ExpectQuery(selectBlocks).WithArgs("eth-blocks", 10000, 20000).WillReturnRows(blocks())
+		mock.ExpectQuery(selectBlocks).WithArgs("eth-blocks", 20000, 20002).WillReturnRows(blocks(20000))
+		missing, _ = store.Missing(ctx, "eth-blocks", 0, 20002)
+		if len(missing) != 20001 || missing[len(missing)-1] != 20001 {
+			t.Errorf("Expected 20001 missing blocks, up to block 20001, got %d", len(missing))
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		store, mock := newMockStore(t)
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "block_progresses" WHERE key = $1 AND block >= $2 AND block < $3`)).
+			WithArgs("eth-blocks", 27, 29).WillReturnResult(sqlmock.NewResult(0, 2))
+		if err := store.Clear(ctx, "eth-blocks", 27, 29); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+}
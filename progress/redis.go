@@ -0,0 +1,77 @@
+package progress
+
+import (
+	"context"
+	"fmt"
+)
+
+// Bitmap is the subset of cache operations needed by RedisStore; *cache.Cache implements it
+type Bitmap interface {
+	SetBit(ctx context.Context, key string, offset int64, value int) error
+	GetRange(ctx context.Context, key string, start, end int64) (string, error)
+}
+
+// RedisStore records block completion in a Redis bitmap, one bit per block number, under "<key>-progress"; Redis
+// caps bitmaps at 2^32 bits, i.e. block numbers must stay under ~4.3 billion
+type RedisStore struct {
+	bitmap Bitmap
+}
+
+// NewRedisStore instantiates a store keeping its bitmaps in the supplied cache
+func NewRedisStore(bitmap Bitmap) *RedisStore {
+	return &RedisStore{bitmap: bitmap}
+}
+
+func (s *RedisStore) MarkComplete(ctx context.Context, key string, block uint64) error {
+	return s.bitmap.SetBit(ctx, bitmapKey(key), int64(block), 1)
+}
+
+// Clear unsets the bits of the blocks in [from, to), one SETBIT per block, as rollbacks only span a few blocks
+func (s *RedisStore) Clear(ctx context.Context, key string, from, to uint64) error {
+	for block := from; block < to; block++ {
+		if err := s.bitmap.SetBit(ctx, bitmapKey(key), int64(block), 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RedisStore) Missing(ctx context.Context, key string, from, to uint64) ([]uint64, error) {
+	missing := []uint64{}
+	if to <= from {
+		return missing, nil
+	}
+
+	//	read the bitmap in chunks, as a range may span millions of blocks
+	const chunkBytes = 1 << 16
+	for start := from / 8; start <= (to-1)/8; start += chunkBytes {
+		end := start + chunkBytes - 1
+		if last := (to - 1) / 8; end > last {
+			end = last
+		}
+		raw, err := s.bitmap.GetRange(ctx, bitmapKey(key), int64(start), int64(end))
+		if err != nil {
+			return nil, err
+		}
+
+		//	bytes past the end of the bitmap were never set; bit 0 is the most significant bit of byte 0
+		for block := max(from, start*8); block < to && block < (end+1)*8; block++ {
+			i := block/8 - start
+			if i >= uint64(len(raw)) || raw[i]&(0x80>>(block%8)) == 0 {
+				missing = append(missing, block)
+			}
+		}
+	}
+	return missing, nil
+}
+
+func bitmapKey(key string) string {
+	return fmt.Sprintf("%s-progress", key)
+}
+
+func max(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,73 @@
+package progress_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/coherentopensource/go-service-framework/progress"
+)
+
+// memoryBitmap mimics Redis' SETBIT and GETRANGE on strings held in memory
+type memoryBitmap map[string][]byte
+
+func (m memoryBitmap) SetBit(ctx context.Context, key string, offset int64, value int) error {
+	raw := m[key]
+	for int64(len(raw)) <= offset/8 {
+		raw = append(raw, 0)
+	}
+	mask := byte(0x80 >> (offset % 8))
+	if value == 1 {
+		raw[offset/8] |= mask
+	} else {
+		raw[offset/8] &^= mask
+	}
+	m[key] = raw
+	return nil
+}
+
+func (m memoryBitmap) GetRange(ctx context.Context, key string, start, end int64) (string, error) {
+	raw := m[key]
+	if start >= int64(len(raw)) {
+		return "", nil
+	}
+	if end >= int64(len(raw)) {
+		end = int64(len(raw)) - 1
+	}
+	return string(raw[start : end+1]), nil
+}
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+	store := progress.NewRedisStore(memoryBitmap{})
+	for block := uint64(10); block < 30; block++ {
+		if block == 13 || block == 24 {
+			continue
+		}
+		store.MarkComplete(ctx, "eth-blocks", block)
+	}
+
+	//	Gaps are reported, along with blocks past the end of the bitmap
+	missing, err := store.Missing(ctx, "eth-blocks", 12, 33)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fmt.Sprint(missing) != "[13 24 30 31 32]" {
+		t.Errorf("Unexpected missing blocks: %v", missing)
+	}
+
+	//	Keys are tracked apart
+	missing, _ = store.Missing(ctx, "eth-traces", 10, 12)
+	if fmt.Sprint(missing) != "[10 11]" {
+		t.Errorf("Unexpected missing blocks for another key: %v", missing)
+	}
+
+	//	Cleared blocks are reported again
+	if err := store.Clear(ctx, "eth-blocks", 27, 29); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	missing, _ = store.Missing(ctx, "eth-blocks", 25, 30)
+	if fmt.Sprint(missing) != "[27 28]" {
+		t.Errorf("Unexpected missing blocks after clearing: %v", missing)
+	}
+}
@@ -0,0 +1,160 @@
+package rate_limiter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/util"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultInitialRate  = 10.0
+	defaultMinRate      = 1.0
+	defaultIncreaseRate = 0.01
+)
+
+// AdaptiveConfig configures an AdaptiveLimiter; rates are in calls per second. Unset rates default sensibly for configs
+// built by hand: InitialRate to MaxRate (or 10), MaxRate to InitialRate, and MinRate to 1, or InitialRate if lower
+type AdaptiveConfig struct {
+	Name        string  `env:"RATE_LIMITER_NAME" envDefault:"default"`
+	InitialRate float64 `env:"RATE_LIMITER_INITIAL_RATE" envDefault:"10"`
+	MinRate     float64 `env:"RATE_LIMITER_MIN_RATE" envDefault:"1"`
+	MaxRate     float64 `env:"RATE_LIMITER_MAX_RATE" envDefault:"10"`
+	Burst       int     `env:"RATE_LIMITER_BURST" envDefault:"1"`
+	//	Increase is added to the rate after every successful call; defaults to 1% of MaxRate
+	Increase float64 `env:"RATE_LIMITER_INCREASE" envDefault:"0.1"`
+	//	Decrease multiplies the rate after every throttled call
+	Decrease float64 `env:"RATE_LIMITER_DECREASE" envDefault:"0.5"`
+	//	Classifier recognizes throttling errors; defaults to DefaultClassifier
+	Classifier Classifier
+	Metrics    util.Metrics
+}
+
+// AdaptiveLimiter is a Limiter that adjusts its rate to what the upstream tolerates: the rate is cut
+// multiplicatively whenever a call is throttled, and recovers additively with every successful call (AIMD). Calls
+// report their outcome with Observe(), which RateLimitedClient.Exec() does automatically
+type AdaptiveLimiter struct {
+	mu           *sync.Mutex
+	cfg          AdaptiveConfig
+	limiter      *rate.Limiter
+	rate         float64
+	blockedUntil time.Time
+}
+
+// NewAdaptiveLimiter instantiates an adaptive limiter, starting at the configured initial rate
+func NewAdaptiveLimiter(cfg AdaptiveConfig) *AdaptiveLimiter {
+	if cfg.Classifier == nil {
+		cfg.Classifier = DefaultClassifier
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = 1
+	}
+	if cfg.Decrease <= 0 || cfg.Decrease >= 1 {
+		cfg.Decrease = 0.5
+	}
+	if cfg.InitialRate <= 0 {
+		cfg.InitialRate = defaultInitialRate
+		if cfg.MaxRate > 0 {
+			cfg.InitialRate = cfg.MaxRate
+		}
+	}
+	if cfg.MaxRate <= 0 {
+		cfg.MaxRate = cfg.InitialRate
+	}
+	if cfg.MinRate <= 0 {
+		cfg.MinRate = math.Min(defaultMinRate, cfg.InitialRate)
+	}
+	if cfg.MaxRate < cfg.MinRate {
+		cfg.MaxRate = cfg.MinRate
+	}
+	if cfg.Increase <= 0 {
+		cfg.Increase = cfg.MaxRate * defaultIncreaseRate
+	}
+	initial := clamp(cfg.InitialRate, cfg.MinRate, cfg.MaxRate)
+
+	l := &AdaptiveLimiter{
+		mu:      &sync.Mutex{},
+		cfg:     cfg,
+		limiter: rate.NewLimiter(rate.Limit(initial), cfg.Burst),
+		rate:    initial,
+	}
+	l.reportRate()
+	return l
+}
+
+// Wait blocks until the limiter allows another call, or until ctx is done; while the upstream's Retry-After hint
+// is in effect, no call is allowed
+func (l *AdaptiveLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	blockedFor := time.Until(l.blockedUntil)
+	l.mu.Unlock()
+
+	if blockedFor > 0 {
+		timer := time.NewTimer(blockedFor)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return l.limiter.Wait(ctx)
+}
+
+// Observe adjusts the rate to the outcome of a call: throttling errors cut it, and hold off every call for as long
+// as the upstream asked, whereas successes raise it; other errors leave it unchanged
+func (l *AdaptiveLimiter) Observe(err error) {
+	throttled, retryAfter := l.cfg.Classifier(err)
+	if !throttled && err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	previous := l.rate
+	if throttled {
+		l.rate = clamp(l.rate*l.cfg.Decrease, l.cfg.MinRate, l.cfg.MaxRate)
+		if until := time.Now().Add(retryAfter); until.After(l.blockedUntil) {
+			l.blockedUntil = until
+		}
+	} else {
+		l.rate = clamp(l.rate+l.cfg.Increase, l.cfg.MinRate, l.cfg.MaxRate)
+	}
+	changed := l.rate != previous
+	if changed {
+		l.limiter.SetLimit(rate.Limit(l.rate))
+	}
+	l.mu.Unlock()
+
+	if changed {
+		l.reportRate()
+	}
+}
+
+// Rate reports the current effective rate, in calls per second
+func (l *AdaptiveLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// reportRate sends the current effective rate as a gauge
+func (l *AdaptiveLimiter) reportRate() {
+	if l.cfg.Metrics == nil {
+		return
+	}
+	l.cfg.Metrics.Gauge("rate_limiter.rate", l.Rate(), []string{fmt.Sprintf("limiter:%s", l.cfg.Name)}, 1.0)
+}
+
+func clamp(val, min, max float64) float64 {
+	if val < min {
+		return min
+	}
+	if val > max {
+		return max
+	}
+	return val
+}
@@ -0,0 +1,104 @@
+package rate_limiter_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/rate_limiter"
+)
+
+func TestAdaptiveLimiter(t *testing.T) {
+	limiter := rate_limiter.NewAdaptiveLimiter(rate_limiter.AdaptiveConfig{
+		Name:        "test",
+		InitialRate: 100,
+		MinRate:     10,
+		MaxRate:     100,
+		Burst:       1,
+		Increase:    5,
+		Decrease:    0.5,
+	})
+	client := rate_limiter.NewClientWithLimiter(0, limiter)
+	ctx := context.Background()
+
+	//	Throttling errors halve the rate, down to the floor; other errors leave it alone
+	throttled := fmt.Errorf("calling upstream: %w", rate_limiter.ErrRateLimited)
+	for i, expected := range []float64{50, 25, 12.5, 10} {
+		client.Exec(ctx, func() error { return throttled })
+		if limiter.Rate() != expected {
+			t.Errorf("Expected rate %v after %d throttled calls, got %v", expected, i+1, limiter.Rate())
+		}
+	}
+	client.Exec(ctx, func() error { return errors.New("bad request") })
+	if limiter.Rate() != 10 {
+		t.Errorf("Expected rate to be unaffected by other errors, got %v", limiter.Rate())
+	}
+
+	//	Successes raise it back gradually
+	client.Exec(ctx, func() error { return nil })
+	client.Exec(ctx, func() error { return nil })
+	if limiter.Rate() != 20 {
+		t.Errorf("Expected rate to recover to 20, got %v", limiter.Rate())
+	}
+
+	//	A Retry-After hint holds off the next call
+	limiter.Observe(&rate_limiter.RetryAfterError{Err: rate_limiter.ErrOverloaded, RetryAfter: 200 * time.Millisecond})
+	started := time.Now()
+	if err := limiter.Wait(ctx); err != nil {
+		t.Fatalf("Unexpected error waiting: %v", err)
+	}
+	if waited := time.Since(started); waited < 200*time.Millisecond {
+		t.Errorf("Expected to wait out the Retry-After hint, waited %v", waited)
+	}
+
+	if got := rate_limiter.ParseRetryAfter("3", time.Now()); got != 3*time.Second {
+		t.Errorf("Expected 3s from Retry-After in seconds, got %v", got)
+	}
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := rate_limiter.ParseRetryAfter("Sun, 01 Jan 2023 00:00:10 GMT", now); got != 10*time.Second {
+		t.Errorf("Expected 10s from Retry-After as a date, got %v", got)
+	}
+}
+
+func TestAdaptiveLimiterDefaults(t *testing.T) {
+	//	A config built by hand with only an initial rate keeps allowing calls, and recovers after being throttled
+	limiter := rate_limiter.NewAdaptiveLimiter(rate_limiter.AdaptiveConfig{InitialRate: 10})
+	client := rate_limiter.NewClientWithLimiter(0, limiter)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if limiter.Rate() != 10 {
+		t.Errorf("Expected to start at the initial rate, got %v", limiter.Rate())
+	}
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(ctx); err != nil {
+			t.Fatalf("Unexpected error on call %d: %v", i+1, err)
+		}
+	}
+
+	client.Exec(ctx, func() error { return rate_limiter.ErrRateLimited })
+	if limiter.Rate() != 5 {
+		t.Errorf("Expected a throttled call to halve the rate, got %v", limiter.Rate())
+	}
+	for i := 0; i < 100; i++ {
+		limiter.Observe(nil)
+	}
+	if limiter.Rate() != 10 {
+		t.Errorf("Expected successes to recover the rate up to the initial one, got %v", limiter.Rate())
+	}
+
+	//	The rate never drops to zero, however often calls are throttled
+	for i := 0; i < 100; i++ {
+		limiter.Observe(rate_limiter.ErrRateLimited)
+	}
+	if limiter.Rate() <= 0 {
+		t.Errorf("Expected a positive floor on the rate, got %v", limiter.Rate())
+	}
+
+	//	A zero-valued config gets a usable rate too
+	if rate := rate_limiter.NewAdaptiveLimiter(rate_limiter.AdaptiveConfig{}).Rate(); rate <= 0 {
+		t.Errorf("Expected a positive default rate, got %v", rate)
+	}
+}
@@ -0,0 +1,65 @@
+package rate_limiter
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrRateLimited marks an error as the upstream refusing a call for exceeding its quota
+	ErrRateLimited = errors.New("rate limited")
+	// ErrOverloaded marks an error as the upstream shedding load
+	ErrOverloaded = errors.New("upstream overloaded")
+)
+
+// RetryAfterError carries the upstream's hint as to when calls may resume, e.g. from a Retry-After header
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error() + " (retry after " + e.RetryAfter.String() + ")"
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// Classifier decides whether an error means that the upstream is throttling calls, returning how long it asked
+// callers to back off, if at all
+type Classifier func(err error) (throttled bool, retryAfter time.Duration)
+
+// DefaultClassifier recognizes ErrRateLimited and ErrOverloaded, along with any RetryAfterError wrapping them
+func DefaultClassifier(err error) (bool, time.Duration) {
+	if err == nil {
+		return false, 0
+	}
+	var retryErr *RetryAfterError
+	if errors.As(err, &retryErr) {
+		return true, retryErr.RetryAfter
+	}
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrOverloaded), 0
+}
+
+// ParseRetryAfter reads a Retry-After header value, given either in seconds or as an HTTP date; it returns zero if
+// the value is missing or malformed
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,16 @@
+package rate_limiter
+
+import (
+	"context"
+)
+
+// Limiter paces calls by blocking in Wait until the next call may proceed; *rate.Limiter, *pool.Throttler and
+// *RedisLimiter all implement it, so any of them can back a RateLimitedClient or pool.WithThrottler()
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// Observer is implemented by limiters that adapt to the outcome of calls, such as AdaptiveLimiter
+type Observer interface {
+	Observe(err error)
+}
@@ -0,0 +1,148 @@
+package rate_limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/util"
+)
+
+var (
+	// ErrLimiterUnavailable is returned by a fail-closed RedisLimiter when Redis can't be reached
+	ErrLimiterUnavailable = errors.New("rate limiter unavailable")
+)
+
+// gcraScript implements the generic cell rate algorithm: the key holds the theoretical arrival time (TAT) of the next
+// call, in microseconds of Redis server time, so that every replica shares one clock. A call is allowed, and the TAT
+// advanced by one emission interval, unless it arrives more than burst intervals ahead of the TAT; otherwise the
+// script returns how long to wait, in microseconds
+const gcraScript = `
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+local newTat = tat + interval
+local allowAt = newTat - interval * burst
+if now < allowAt then
+	return allowAt - now
+end
+redis.call('SET', KEYS[1], newTat, 'PX', math.max(1, math.ceil((newTat - now) / 1000)))
+return 0
+`
+
+// Scripter runs Lua scripts against Redis; *cache.Cache implements it
+type Scripter interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// RedisLimiter is a Limiter shared by every replica using the same Redis key, so that together they stay within a
+// single quota, such as that of a paid RPC provider
+type RedisLimiter struct {
+	store    Scripter
+	key      string
+	interval time.Duration
+	burst    int
+	failOpen bool
+	logger   util.Logger
+}
+
+type redisOpt func(l *RedisLimiter)
+
+// WithBurst lets up to burst calls through at once after an idle period; the default is 1
+func WithBurst(burst int) redisOpt {
+	return func(l *RedisLimiter) {
+		l.burst = burst
+	}
+}
+
+// WithFailOpen lets calls through unthrottled while Redis is unavailable; by default, Wait returns
+// ErrLimiterUnavailable instead
+func WithFailOpen() redisOpt {
+	return func(l *RedisLimiter) {
+		l.failOpen = true
+	}
+}
+
+// WithLogger specifies a logger for reporting Redis failures
+func WithLogger(logger util.Logger) redisOpt {
+	return func(l *RedisLimiter) {
+		l.logger = logger
+	}
+}
+
+// NewRedisLimiter instantiates a limiter allowing events calls per duration across every replica sharing key; the
+// interval between calls, per / events, must be at least a microsecond, the resolution of the shared clock
+func NewRedisLimiter(store Scripter, key string, events int, per time.Duration, opts ...redisOpt) (*RedisLimiter, error) {
+	if events <= 0 || per <= 0 {
+		return nil, fmt.Errorf("rate limiter [%s]: events and duration must be positive, got %d per %v", key, events, per)
+	}
+	interval := per / time.Duration(events)
+	if interval < time.Microsecond {
+		return nil, fmt.Errorf("rate limiter [%s]: %d events per %v is finer than a microsecond", key, events, per)
+	}
+
+	l := RedisLimiter{
+		store:    store,
+		key:      key,
+		interval: interval,
+		burst:    1,
+	}
+	for _, opt := range opts {
+		opt(&l)
+	}
+	if l.burst <= 0 {
+		return nil, fmt.Errorf("rate limiter [%s]: burst must be positive, got %d", key, l.burst)
+	}
+
+	return &l, nil
+}
+
+// Wait blocks until the shared quota allows another call, or until ctx is done
+func (l *RedisLimiter) Wait(ctx context.Context) error {
+	for {
+		wait, err := l.reserve(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if l.logger != nil {
+				l.logger.Errorf("Rate limiter [%s] could not reach Redis: %v", l.key, err)
+			}
+			if l.failOpen {
+				return nil
+			}
+			return fmt.Errorf("rate limiter [%s]: %w: %v", l.key, ErrLimiterUnavailable, err)
+		}
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve attempts to take a call from the shared quota, returning how long to wait before trying again if none is
+// available
+func (l *RedisLimiter) reserve(ctx context.Context) (time.Duration, error) {
+	res, err := l.store.Eval(ctx, gcraScript, []string{l.key}, l.interval.Microseconds(), l.burst)
+	if err != nil {
+		return 0, err
+	}
+	wait, ok := res.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply from rate limiter script: %v", res)
+	}
+	return time.Duration(wait) * time.Microsecond, nil
+}
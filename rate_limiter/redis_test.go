@@ -0,0 +1,89 @@
+package rate_limiter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/rate_limiter"
+)
+
+// fakeScripter replays a queue of script replies, standing in for Redis
+type fakeScripter struct {
+	replies []interface{}
+	err     error
+	calls   int
+	args    []interface{}
+}
+
+func (f *fakeScripter) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	f.calls++
+	f.args = args
+	if f.err != nil {
+		return nil, f.err
+	}
+	reply := f.replies[0]
+	if len(f.replies) > 1 {
+		f.replies = f.replies[1:]
+	}
+	return reply, nil
+}
+
+func TestRedisLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	//	Invalid quotas are refused up front
+	for _, events := range []int{0, -1, 2000000} {
+		if _, err := rate_limiter.NewRedisLimiter(&fakeScripter{}, "rpc", events, time.Second); err == nil {
+			t.Errorf("Expected %d events per second to be refused", events)
+		}
+	}
+	if _, err := rate_limiter.NewRedisLimiter(&fakeScripter{}, "rpc", 10, time.Second, rate_limiter.WithBurst(0)); err == nil {
+		t.Error("Expected a zero burst to be refused")
+	}
+
+	//	An allowed call returns at once, passing the interval in microseconds and the burst to the script
+	store := &fakeScripter{replies: []interface{}{int64(0)}}
+	limiter, err := rate_limiter.NewRedisLimiter(store, "rpc", 10, time.Second, rate_limiter.WithBurst(5))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := limiter.Wait(ctx); err != nil || store.calls != 1 {
+		t.Errorf("Expected a single allowed call, got %d calls: %v", store.calls, err)
+	}
+	if store.args[0] != int64(100000) || store.args[1] != 5 {
+		t.Errorf("Unexpected script arguments: %v", store.args)
+	}
+
+	//	A denied call waits as long as the script says, then tries again
+	store = &fakeScripter{replies: []interface{}{int64(50000), int64(0)}}
+	limiter, _ = rate_limiter.NewRedisLimiter(store, "rpc", 10, time.Second)
+	started := time.Now()
+	if err := limiter.Wait(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if waited := time.Since(started); waited < 50*time.Millisecond || store.calls != 2 {
+		t.Errorf("Expected to wait 50ms then retry, waited %v over %d calls", waited, store.calls)
+	}
+
+	//	A denied call gives up once its context is done
+	store = &fakeScripter{replies: []interface{}{int64(time.Hour / time.Microsecond)}}
+	limiter, _ = rate_limiter.NewRedisLimiter(store, "rpc", 10, time.Second)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(timeoutCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to cut the wait short, got: %v", err)
+	}
+
+	//	With Redis down, a fail-closed limiter refuses calls while a fail-open one lets them through
+	down := errors.New("connection refused")
+	closed, _ := rate_limiter.NewRedisLimiter(&fakeScripter{err: down}, "rpc", 10, time.Second)
+	if err := closed.Wait(ctx); !errors.Is(err, rate_limiter.ErrLimiterUnavailable) {
+		t.Errorf("Expected a fail-closed limiter to refuse the call, got: %v", err)
+	}
+	open, _ := rate_limiter.NewRedisLimiter(&fakeScripter{err: down}, "rpc", 10, time.Second, rate_limiter.WithFailOpen())
+	if err := open.Wait(ctx); err != nil {
+		t.Errorf("Expected a fail-open limiter to allow the call, got: %v", err)
+	}
+}
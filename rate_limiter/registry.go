@@ -0,0 +1,203 @@
+package rate_limiter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limit is a quota of Events calls per Per duration, with bursts of up to Burst calls (defaulting to Events); as
+// text, it reads "events/duration" or "events/duration:burst", e.g. "20/1s:5"
+type Limit struct {
+	Events int
+	Per    time.Duration
+	Burst  int
+}
+
+func (l *Limit) UnmarshalText(text []byte) error {
+	spec, burst, hasBurst := strings.Cut(string(text), ":")
+	events, per, ok := strings.Cut(spec, "/")
+	if !ok {
+		return fmt.Errorf("invalid limit [%s]: expected events/duration", text)
+	}
+
+	var err error
+	if l.Events, err = strconv.Atoi(strings.TrimSpace(events)); err != nil || l.Events <= 0 {
+		return fmt.Errorf("invalid limit [%s]: bad event count", text)
+	}
+	if l.Per, err = time.ParseDuration(strings.TrimSpace(per)); err != nil || l.Per <= 0 {
+		return fmt.Errorf("invalid limit [%s]: bad duration", text)
+	}
+	l.Burst = l.Events
+	if hasBurst {
+		if l.Burst, err = strconv.Atoi(strings.TrimSpace(burst)); err != nil || l.Burst <= 0 {
+			return fmt.Errorf("invalid limit [%s]: bad burst", text)
+		}
+	}
+	return nil
+}
+
+// Limits maps keys to their quotas; as text, it reads "key=limit,key=limit", e.g. "trace=20/1s:5,metadata=5/1s"
+type Limits map[string]Limit
+
+func (l *Limits) UnmarshalText(text []byte) error {
+	limits := Limits{}
+	for _, entry := range strings.Split(string(text), ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		key, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("invalid limit entry [%s]: expected key=limit", entry)
+		}
+		var limit Limit
+		if err := limit.UnmarshalText([]byte(spec)); err != nil {
+			return err
+		}
+		limits[strings.TrimSpace(key)] = limit
+	}
+	*l = limits
+	return nil
+}
+
+// RegistryConfig configures a Registry; keys without a limit of their own share the default quota, each with a
+// limiter of its own
+type RegistryConfig struct {
+	Default    Limit         `env:"RATE_LIMIT_DEFAULT" envDefault:"10/1s"`
+	Limits     Limits        `env:"RATE_LIMITS"`
+	IdleTTL    time.Duration `env:"RATE_LIMIT_IDLE_TTL" envDefault:"10m"`
+	ErrorSleep time.Duration `env:"RATE_LIMIT_ERROR_SLEEP" envDefault:"1s"`
+}
+
+// LimiterFactory builds the limiter of a key, e.g. a RedisLimiter to share the quota across replicas
+type LimiterFactory func(key string, limit Limit) Limiter
+
+// Registry holds a rate limited client per key, e.g. per upstream endpoint, each with its own quota. Clients are
+// created on first use, and evicted once idle for longer than the configured TTL
+type Registry struct {
+	mu        *sync.Mutex
+	cfg       RegistryConfig
+	factory   LimiterFactory
+	clients   map[string]*registryEntry
+	lastSweep time.Time
+}
+
+type registryEntry struct {
+	client   *RateLimitedClient
+	lastUsed time.Time
+	inFlight int
+}
+
+type registryOpt func(r *Registry)
+
+// WithLimiterFactory specifies how limiters are built; by default, each key gets a local token bucket
+func WithLimiterFactory(factory LimiterFactory) registryOpt {
+	return func(r *Registry) {
+		r.factory = factory
+	}
+}
+
+// NewRegistry instantiates a registry of rate limited clients
+func NewRegistry(cfg RegistryConfig, opts ...registryOpt) *Registry {
+	r := Registry{
+		mu:        &sync.Mutex{},
+		cfg:       cfg,
+		factory:   localLimiter,
+		clients:   map[string]*registryEntry{},
+		lastSweep: time.Now(),
+	}
+	for _, opt := range opts {
+		opt(&r)
+	}
+
+	return &r
+}
+
+// ExecKey runs fn with the client of the given key; the key counts as in use, and is not evicted, until fn returns
+func (r *Registry) ExecKey(ctx context.Context, key string, fn RunnerFunc) error {
+	entry := r.acquire(key)
+	defer r.release(entry)
+	return entry.client.Exec(ctx, fn)
+}
+
+// Client returns the client of the given key, creating it if needed; callers holding on to the client past its
+// idle TTL should use ExecKey() instead, as the key may be evicted in the meantime
+func (r *Registry) Client(key string) *RateLimitedClient {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.entry(key).client
+}
+
+// acquire returns the entry of the given key, counting it as in use until released
+func (r *Registry) acquire(key string) *registryEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry := r.entry(key)
+	entry.inFlight++
+	return entry
+}
+
+// release marks the end of a call acquired with acquire()
+func (r *Registry) release(entry *registryEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry.inFlight--
+	entry.lastUsed = time.Now()
+}
+
+// entry returns the entry of the given key, creating it if needed; r.mu must be held
+func (r *Registry) entry(key string) *registryEntry {
+	now := time.Now()
+	r.evictIdle(now)
+	entry, ok := r.clients[key]
+	if !ok {
+		entry = &registryEntry{client: NewClientWithLimiter(r.cfg.ErrorSleep, r.factory(key, r.limitOf(key)))}
+		r.clients[key] = entry
+	}
+	entry.lastUsed = now
+	return entry
+}
+
+// Len counts the clients currently held
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.clients)
+}
+
+func (r *Registry) limitOf(key string) Limit {
+	if limit, ok := r.cfg.Limits[key]; ok {
+		return limit
+	}
+	return r.cfg.Default
+}
+
+// evictIdle drops the clients unused for longer than the idle TTL, checking at most once per TTL; clients with calls
+// in flight through ExecKey() are kept
+func (r *Registry) evictIdle(now time.Time) {
+	if r.cfg.IdleTTL <= 0 || now.Sub(r.lastSweep) < r.cfg.IdleTTL {
+		return
+	}
+	r.lastSweep = now
+	for key, entry := range r.clients {
+		if entry.inFlight == 0 && now.Sub(entry.lastUsed) > r.cfg.IdleTTL {
+			delete(r.clients, key)
+		}
+	}
+}
+
+func localLimiter(_ string, limit Limit) Limiter {
+	if limit.Events <= 0 || limit.Per <= 0 {
+		return rate.NewLimiter(rate.Inf, 1)
+	}
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = limit.Events
+	}
+	return rate.NewLimiter(rate.Every(limit.Per/time.Duration(limit.Events)), burst)
+}
@@ -0,0 +1,71 @@
+package rate_limiter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/rate_limiter"
+)
+
+func TestRegistry(t *testing.T) {
+	var limits rate_limiter.Limits
+	if err := limits.UnmarshalText([]byte("trace=2/1s:1, metadata=100/1s")); err != nil {
+		t.Fatalf("Error parsing limits: %v", err)
+	}
+	if limits["trace"] != (rate_limiter.Limit{Events: 2, Per: time.Second, Burst: 1}) {
+		t.Errorf("Unexpected trace limit: %+v", limits["trace"])
+	}
+
+	registry := rate_limiter.NewRegistry(rate_limiter.RegistryConfig{
+		Default: rate_limiter.Limit{Events: 1000, Per: time.Second},
+		Limits:  limits,
+		IdleTTL: 100 * time.Millisecond,
+	})
+	ctx := context.Background()
+	noop := func() error { return nil }
+
+	//	Keys are limited independently: the trace quota paces its own calls only
+	started := time.Now()
+	for i := 0; i < 3; i++ {
+		registry.ExecKey(ctx, "trace", noop)
+		registry.ExecKey(ctx, "archive", noop)
+	}
+	if waited := time.Since(started); waited < time.Second {
+		t.Errorf("Expected trace calls to be paced at 2/s, took %v", waited)
+	}
+	if registry.Len() != 2 {
+		t.Errorf("Expected 2 clients, got %d", registry.Len())
+	}
+
+	//	Idle keys are evicted
+	time.Sleep(150 * time.Millisecond)
+	registry.ExecKey(ctx, "metadata", noop)
+	if registry.Len() != 1 {
+		t.Errorf("Expected idle clients to be evicted, got %d", registry.Len())
+	}
+	//	Keys in use are kept past their TTL until their calls return
+	release, running := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		registry.ExecKey(ctx, "trace", func() error {
+			close(running)
+			<-release
+			return nil
+		})
+	}()
+	<-running
+	time.Sleep(150 * time.Millisecond)
+	registry.ExecKey(ctx, "archive", noop)
+	if registry.Len() != 2 {
+		t.Errorf("Expected the trace client in use to be kept while metadata is evicted, got %d clients", registry.Len())
+	}
+	close(release)
+	<-done
+	time.Sleep(150 * time.Millisecond)
+	registry.ExecKey(ctx, "archive", noop)
+	if registry.Len() != 1 {
+		t.Errorf("Expected the client to be evicted once idle, got %d", registry.Len())
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 type RateLimitedClient struct {
 	RateLimiter     *rate.Limiter
+	Limiter         Limiter       // overrides RateLimiter, e.g. with a RedisLimiter shared across replicas
 	ErrorSleep      time.Duration // sleep period if the server is not ok
 	RateIntervalMs  time.Duration // measurement unit for rate limiter (5 / second) -> 1 * time.Second
 	MaxRateRequests int           // max requests per measurement unit (5 / second) -> 5
@@ -21,16 +22,51 @@ func NewClient(errorSleep time.Duration, rateIntervalMs time.Duration, maxRateRe
 	}
 }
 
+// NewClientWithLimiter builds a client paced by the supplied limiter
+func NewClientWithLimiter(errorSleep time.Duration, limiter Limiter) *RateLimitedClient {
+	return &RateLimitedClient{
+		Limiter:    limiter,
+		ErrorSleep: errorSleep,
+	}
+}
+
+// NewAdaptiveClient builds a client paced by an AdaptiveLimiter
+func NewAdaptiveClient(errorSleep time.Duration, cfg AdaptiveConfig) *RateLimitedClient {
+	return NewClientWithLimiter(errorSleep, NewAdaptiveLimiter(cfg))
+}
+
+// Exec runs fn once the limiter allows it, reporting the outcome to limiters that adapt to it. With a limiter that
+// doesn't, a throttling error holds the caller for ErrorSleep, or for as long as the upstream asked, before returning
 func (r *RateLimitedClient) Exec(ctx context.Context, fn RunnerFunc) error {
-	if err := r.RateLimiter.Wait(ctx); err != nil {
+	limiter := r.limiter()
+	if err := limiter.Wait(ctx); err != nil {
 		return err
 	}
-	var err error
-	err = fn()
-	if err == nil {
-		return nil
+	err := fn()
+	if observer, ok := limiter.(Observer); ok {
+		observer.Observe(err)
+		return err
+	}
+
+	if throttled, retryAfter := DefaultClassifier(err); throttled {
+		if retryAfter < r.ErrorSleep {
+			retryAfter = r.ErrorSleep
+		}
+		timer := time.NewTimer(retryAfter)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
 	}
 	return err
 }
 
+func (r *RateLimitedClient) limiter() Limiter {
+	if r.Limiter != nil {
+		return r.Limiter
+	}
+	return r.RateLimiter
+}
+
 type RunnerFunc func() error
@@ -1,36 +1,182 @@
 package retry
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math/rand"
 	"time"
 )
 
+const (
+	maxDefaultSleep = time.Minute
+)
+
 var errMaxRetriesReached = errors.New("exceeded retry limit")
 
 type RunnerFunc func() error
 type SleeperFunc func(attempt int)
 
-func Exec(maxRetries int, fn RunnerFunc, sleep SleeperFunc) error {
-	if sleep == nil {
-		sleep = DefaultSleeper
+// Backoff computes the delay before the next attempt, given the number of attempts made so far and the previous delay
+type Backoff interface {
+	Delay(attempt int, prev time.Duration) time.Duration
+}
+
+// Policy determines how often, and after how long, Do retries a failing function
+type Policy struct {
+	Backoff Backoff
+	//	MaxAttempts is the total number of times the function may run, including the first attempt; unlimited if zero
+	MaxAttempts int
+	//	MaxElapsed gives up once the next attempt would start later than this after the first one; unlimited if zero
+	MaxElapsed time.Duration
+	//	OnRetry is called before sleeping ahead of every retry, e.g. for logging and metrics
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// Exponential returns a policy doubling the delay after every attempt, from initial up to max
+func Exponential(initial, max time.Duration) Policy {
+	return Policy{Backoff: exponential{initial: initial, max: max}}
+}
+
+// DecorrelatedJitter returns a policy with delays picked at random between base and three times the previous delay,
+// up to max; this spreads out the retries of callers that failed together better than plain exponential backoff
+func DecorrelatedJitter(base, max time.Duration) Policy {
+	return Policy{Backoff: decorrelatedJitter{base: base, max: max}}
+}
+
+// Constant returns a policy with the same delay between all attempts
+func Constant(delay time.Duration) Policy {
+	return Policy{Backoff: constant(delay)}
+}
+
+type exponential struct {
+	initial, max time.Duration
+}
+
+func (b exponential) Delay(attempt int, _ time.Duration) time.Duration {
+	delay := b.initial
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if b.max > 0 && delay >= b.max {
+			return b.max
+		}
+	}
+	return delay
+}
+
+type decorrelatedJitter struct {
+	base, max time.Duration
+}
+
+func (b decorrelatedJitter) Delay(_ int, prev time.Duration) time.Duration {
+	upper := prev * 3
+	if upper <= b.base {
+		return b.base
+	}
+	delay := b.base + time.Duration(rand.Int63n(int64(upper-b.base)))
+	if b.max > 0 && delay > b.max {
+		return b.max
+	}
+	return delay
+}
+
+type constant time.Duration
+
+func (b constant) Delay(int, time.Duration) time.Duration {
+	return time.Duration(b)
+}
+
+// permanentError marks an error as not worth retrying
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps an error to stop Do from retrying; Do returns the wrapped error itself
+func Permanent(err error) error {
+	if err == nil {
+		return nil
 	}
-	var err error
-	attempt := 1
-	for {
-		err = fn()
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err, or an error it wraps, was marked as not worth retrying with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// Do runs fn until it succeeds, returns a Permanent error, or the policy gives up, sleeping between attempts as the
+// policy dictates; it returns the error of the last attempt, wrapped with ctx's error if ctx is done first
+func Do(ctx context.Context, policy Policy, fn RunnerFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	started := time.Now()
+	var delay time.Duration
+	for attempt := 1; ; attempt++ {
+		err := fn()
 		if err == nil {
 			return nil
 		}
-		attempt++
-		if attempt > maxRetries {
-			break
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			return err
+		}
+
+		if policy.Backoff != nil {
+			delay = policy.Backoff.Delay(attempt, delay)
+		}
+		if policy.MaxElapsed > 0 && time.Since(started)+delay > policy.MaxElapsed {
+			return err
+		}
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w after %d attempts: %w", ctx.Err(), attempt, err)
+		case <-timer.C:
 		}
-		sleep(attempt)
-		continue
 	}
-	return err
 }
+
+// Exec runs fn up to maxRetries times, calling sleep between attempts
+//
+// Deprecated: use Do, which stops sleeping once its context is done, and tells permanent errors from retryable ones
+func Exec(maxRetries int, fn RunnerFunc, sleep SleeperFunc) error {
+	if sleep == nil {
+		sleep = DefaultSleeper
+	}
+	if maxRetries < 1 {
+		maxRetries = 1
+	}
+	return Do(context.Background(), Policy{MaxAttempts: maxRetries, Backoff: sleeperBackoff(sleep)}, fn)
+}
+
+// sleeperBackoff adapts a SleeperFunc, which sleeps by itself, to a Backoff
+type sleeperBackoff SleeperFunc
+
+func (b sleeperBackoff) Delay(attempt int, _ time.Duration) time.Duration {
+	b(attempt + 1)
+	return 0
+}
+
+// DefaultSleeper sleeps for 2^(attempt-1) seconds, up to a minute
 func DefaultSleeper(attempt int) {
-	exp := time.Duration(2 ^ (attempt - 1))
-	time.Sleep(exp * time.Second)
+	time.Sleep(exponential{initial: time.Second, max: maxDefaultSleep}.Delay(attempt, 0))
 }
@@ -0,0 +1,61 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coherentopensource/go-service-framework/retry"
+)
+
+func TestDo(t *testing.T) {
+	failure := errors.New("node unavailable")
+
+	//	Delays double up to the cap, and the hook sees every retry
+	var delays []time.Duration
+	policy := retry.Exponential(time.Millisecond, 4*time.Millisecond)
+	policy.MaxAttempts = 5
+	policy.OnRetry = func(attempt int, err error, delay time.Duration) {
+		delays = append(delays, delay)
+	}
+	attempts := 0
+	err := retry.Do(context.Background(), policy, func() error {
+		attempts++
+		return failure
+	})
+	if !errors.Is(err, failure) || attempts != 5 {
+		t.Errorf("Expected 5 failed attempts, got %d: %v", attempts, err)
+	}
+	expected := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond}
+	for i := range expected {
+		if i >= len(delays) || delays[i] != expected[i] {
+			t.Fatalf("Expected delays %v, got %v", expected, delays)
+		}
+	}
+
+	//	Permanent errors stop retries, and are returned unwrapped
+	attempts = 0
+	err = retry.Do(context.Background(), retry.Constant(0), func() error {
+		attempts++
+		return retry.Permanent(failure)
+	})
+	if err != failure || attempts != 1 {
+		t.Errorf("Expected a single attempt returning the permanent error, got %d: %v", attempts, err)
+	}
+
+	//	Retries stop once the context is done, or the time is up
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = retry.Do(ctx, retry.Constant(time.Hour), func() error { return failure })
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, failure) {
+		t.Errorf("Expected the deadline and last error, got: %v", err)
+	}
+	jitter := retry.DecorrelatedJitter(10*time.Millisecond, time.Second)
+	jitter.MaxElapsed = 100 * time.Millisecond
+	started := time.Now()
+	retry.Do(context.Background(), jitter, func() error { return failure })
+	if elapsed := time.Since(started); elapsed > 200*time.Millisecond {
+		t.Errorf("Expected to give up within the max elapsed time, took %v", elapsed)
+	}
+}
@@ -0,0 +1,15 @@
+package tracing
+
+import (
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewInMemory builds a tracer whose spans are exported synchronously to an in-memory exporter as they end, so that
+// tests can assert on the spans produced by pools and pollers
+func NewInMemory(name string) (trace.Tracer, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return provider.Tracer(name), exporter
+}
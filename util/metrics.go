@@ -1,6 +1,10 @@
 package util
 
-import "github.com/DataDog/datadog-go/v5/statsd"
+import (
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
 
 type Metrics interface {
 	Incr(name string, tags []string, rate float64) error
@@ -12,3 +16,10 @@ type Metrics interface {
 	SimpleEvent(title, text string) error
 	Event(e *statsd.Event) error
 }
+
+// DistributionMetrics is implemented by Metrics clients that also report distributions, such as the Datadog client;
+// it is checked for with a type assertion, so that Metrics implementations without it keep working
+type DistributionMetrics interface {
+	Histogram(name string, value float64, tags []string, rate float64) error
+	Timing(name string, value time.Duration, tags []string, rate float64) error
+}